@@ -1,21 +1,21 @@
 package goib
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-
-	l5g "github.com/neocortical/log5go"
+	"sync"
+	"time"
 )
 
-const urlTemplate = "http://{host}/v2.0/delivery/{channel}/json/{service}"
+const urlTemplate = "{scheme}://{host}/{version}/delivery/{channel}/json/{service}"
 const defaultHost = "ibsys-api.ib-prod.com"
 
-var log = l5g.Logger(l5g.LogAll)
-
 type httpClient func(url string) ([]byte, error)
 
 // API is the entrance point for interacting with the IB API
@@ -27,56 +27,131 @@ type API interface {
 	ContentItems(channel string, contentID int, params url.Values) ([]Item, error)
 	Closings(channel string, filter ClosingsFilter, providerID ...string) (ClosingsResponse, error)
 	UnmarshalReceiver(r Receiver) (Item, error)
+
+	APIContext
 }
 
-// NewAPI constructs an API object for the given channel
-func NewAPI() API {
-	return NewAPIWithHost(defaultHost)
+// APIContext mirrors the request methods of API, but takes a context that is carried
+// through to the underlying HTTP request. Cancelling the context or letting its deadline
+// pass aborts the in-flight call to IB.
+type APIContext interface {
+	EntryContext(ctx context.Context, channel string, entrytype string, params url.Values) (Item, error)
+	SearchContext(ctx context.Context, channel string, query string, params url.Values) (*Collection, error)
+	ContentContext(ctx context.Context, channel string, contentID int, params url.Values) (Item, error)
+	ContentMediaContext(ctx context.Context, channel string, contentID int, params url.Values) ([]Item, error)
+	ContentItemsContext(ctx context.Context, channel string, contentID int, params url.Values) ([]Item, error)
+	ClosingsContext(ctx context.Context, channel string, filter ClosingsFilter, providerID ...string) (ClosingsResponse, error)
 }
 
-func NewAPIWithHost(host string) API {
-	return &api{
-		host:   host,
-		client: netClient,
+// NewAPI constructs an API object configured by the supplied options
+func NewAPI(opts ...Option) API {
+	a := &api{
+		scheme:      defaultScheme,
+		host:        defaultHost,
+		version:     defaultVersion,
+		client:      netClient,
+		maxBodySize: defaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
+	a.setupClient()
+	a.setupLogger()
+	a.warnIfBuffered()
+
+	return a
 }
 
-type api struct {
-	host   string
-	client *http.Client
+// NewAPIWithHost constructs an API object that sends requests to the given host
+func NewAPIWithHost(host string) API {
+	return NewAPI(WithHost(host))
 }
 
-func (api *api) Entry(channel string, entrytype string, params url.Values) (entry Item, err error) {
+type api struct {
+	scheme        string
+	host          string
+	version       string
+	client        *http.Client
+	transport     http.RoundTripper
+	middleware    []Middleware
+	timeout       time.Duration
+	userAgent     string
+	defaultParams url.Values
+	retryPolicy   RetryPolicy
+	breakers      *circuitBreakers
+	cache         *CacheConfig
+	refreshMu     sync.Mutex
+	refreshing    map[string]struct{}
+	flights       *flightGroup
+	failover      *FailoverSettings
+	hosts         *hostPool
+	limiters      *rateLimiters
+	maxBodySize   int64
+	streaming     bool
+	metrics       Metrics
+	tracer        Tracer
+	logger        Logger
+	types         map[ItemType]DecoderFunc
+	genericItems  bool
+	rawJSON       bool
+	strict        bool
+}
+
+// request describes a single call to IB
+type request struct {
+	channel   string
+	service   string
+	entryType string
+	contentID int
+	uri       string
+
+	// stream, if set, decodes a successful response while its body is being read
+	stream func(body io.Reader) (*response, error)
+	// report collects the sub-objects dropped while decoding the response
+	report *DecodeReport
+	// strict makes the first dropped sub-object, kept in failure, fail the call
+	strict  bool
+	failure error
+}
+
+func (api *api) Entry(channel string, entrytype string, params url.Values) (Item, error) {
+	return api.EntryContext(context.Background(), channel, entrytype, params)
+}
+
+func (api *api) EntryContext(ctx context.Context, channel string, entrytype string, params url.Values) (entry Item, err error) {
 	uri := api.setupURI(channel, "entry")
 	uri += "/" + entrytype
+	uri += api.encodeQuery(params)
 
-	if len(params) > 0 {
-		uri += "?" + params.Encode()
-	}
-
-	bytes, err := api.doGet(uri)
+	req := &request{channel: channel, service: "entry", entryType: entrytype, uri: uri, strict: api.decodesStrictly(ctx)}
+	resp, err := api.doGet(ctx, req)
 	if err != nil {
 		return entry, err
 	}
 
-	return api.unmarshalResponse(bytes)
+	return api.unmarshalResponse(resp, req)
+}
+
+func (api *api) Search(channel string, query string, params url.Values) (*Collection, error) {
+	return api.SearchContext(context.Background(), channel, query, params)
 }
 
-func (api *api) Search(channel string, query string, params url.Values) (s *Collection, err error) {
+func (api *api) SearchContext(ctx context.Context, channel string, query string, params url.Values) (s *Collection, err error) {
 	uri := api.setupURI(channel, "search")
 
 	if params == nil {
 		params = url.Values{}
 	}
 	params.Set("q", query)
-	uri += "?" + params.Encode()
+	uri += api.encodeQuery(params)
 
-	bytes, err := api.doGet(uri)
+	req := &request{channel: channel, service: "search", uri: uri, strict: api.decodesStrictly(ctx)}
+	resp, err := api.doGet(ctx, req)
 	if err != nil {
 		return s, err
 	}
 
-	r, err := api.unmarshalResponse(bytes)
+	r, err := api.unmarshalResponse(resp, req)
 	if err != nil {
 		return s, err
 	}
@@ -89,102 +164,185 @@ func (api *api) Search(channel string, query string, params url.Values) (s *Coll
 }
 
 func (api *api) Content(channel string, contentID int, params url.Values) (Item, error) {
+	return api.ContentContext(context.Background(), channel, contentID, params)
+}
+
+func (api *api) ContentContext(ctx context.Context, channel string, contentID int, params url.Values) (Item, error) {
 	uri := api.setupURI(channel, "content")
 	uri += "/" + strconv.Itoa(contentID)
+	uri += api.encodeQuery(params)
 
-	if len(params) > 0 {
-		uri += "?" + params.Encode()
-	}
-
-	bytes, err := api.doGet(uri)
+	req := &request{channel: channel, service: "content", contentID: contentID, uri: uri, strict: api.decodesStrictly(ctx)}
+	resp, err := api.doGet(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return api.unmarshalResponse(bytes)
+	return api.unmarshalResponse(resp, req)
 }
 
 func (api *api) ContentMedia(channel string, contentID int, params url.Values) ([]Item, error) {
+	return api.ContentMediaContext(context.Background(), channel, contentID, params)
+}
+
+func (api *api) ContentMediaContext(ctx context.Context, channel string, contentID int, params url.Values) ([]Item, error) {
 	uri := api.setupURI(channel, "content")
 	uri += "/" + strconv.Itoa(contentID) + "/media"
+	uri += api.encodeQuery(params)
 
-	if len(params) > 0 {
-		uri += "?" + params.Encode()
-	}
-
-	bytes, err := api.doGet(uri)
+	req := &request{channel: channel, service: "content", contentID: contentID, uri: uri, strict: api.decodesStrictly(ctx)}
+	resp, err := api.doGet(ctx, api.streamArray(req))
 	if err != nil {
 		return nil, err
 	}
 
-	return api.unmarshalArrayResponse(bytes)
+	return api.unmarshalArrayResponse(resp, req)
 }
 
 func (api *api) ContentItems(channel string, contentID int, params url.Values) ([]Item, error) {
+	return api.ContentItemsContext(context.Background(), channel, contentID, params)
+}
+
+func (api *api) ContentItemsContext(ctx context.Context, channel string, contentID int, params url.Values) ([]Item, error) {
 	uri := api.setupURI(channel, "content")
 	uri += "/" + strconv.Itoa(contentID) + "/items"
+	uri += api.encodeQuery(params)
 
-	if len(params) > 0 {
-		uri += "?" + params.Encode()
-	}
-
-	bytes, err := api.doGet(uri)
+	req := &request{channel: channel, service: "content", contentID: contentID, uri: uri, strict: api.decodesStrictly(ctx)}
+	resp, err := api.doGet(ctx, api.streamArray(req))
 	if err != nil {
 		return nil, err
 	}
 
-	return api.unmarshalArrayResponse(bytes)
+	return api.unmarshalArrayResponse(resp, req)
 }
 
 func (api *api) Closings(channel string, filter ClosingsFilter, providerID ...string) (ClosingsResponse, error) {
+	return api.ClosingsContext(context.Background(), channel, filter, providerID...)
+}
+
+func (api *api) ClosingsContext(ctx context.Context, channel string, filter ClosingsFilter, providerID ...string) (ClosingsResponse, error) {
 	uri := api.setupURI(channel, "closings")
 	uri += "/" + string(filter)
 	if filter == ClosingsInst && len(providerID) > 0 {
 		uri += "/id/" + providerID[0]
 	}
+	uri += api.encodeQuery(nil)
 
-	bytes, err := api.doGet(uri)
+	req := &request{channel: channel, service: "closings", uri: uri}
+	resp, err := api.doGet(ctx, req)
 	if err != nil {
 		return ClosingsResponse{}, err
 	}
 
-	return unmarshalClosingsResponse(bytes)
+	result, err := unmarshalClosingsResponse(resp.body)
+	if err != nil {
+		return result, api.decodeFailed(resp, req, err)
+	}
+	return result, nil
 }
 
 func (api *api) setupURI(channel, service string) string {
-	uri := strings.Replace(urlTemplate, "{host}", api.host, 1)
+	uri := strings.Replace(urlTemplate, "{scheme}", api.scheme, 1)
+	uri = strings.Replace(uri, "{host}", api.host, 1)
+	uri = strings.Replace(uri, "{version}", api.version, 1)
 	uri = strings.Replace(uri, "{channel}", channel, 1)
 	return strings.Replace(uri, "{service}", service, 1)
 }
 
-func (api *api) unmarshalResponse(bytes []byte) (Item, error) {
+// encodeQuery merges the default params into the supplied params and returns the
+// resulting query string, including the leading "?", or "" if there are no params
+func (api *api) encodeQuery(params url.Values) string {
+	query := url.Values{}
+	for k, v := range api.defaultParams {
+		query[k] = v
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+func (api *api) unmarshalResponse(resp *response, req *request) (Item, error) {
 	var r Receiver
 
-	err := json.Unmarshal(bytes, &r)
-	if err != nil {
-		return nil, err
+	if api.keepsRaw() {
+		// decoding object by object captures the raw JSON of each one on the way
+		var err error
+		if r, err = api.decodeReceiver(api.ownBody(resp), req); err != nil {
+			return nil, api.decodeFailed(resp, req, err)
+		}
+	} else if err := json.Unmarshal(resp.body, &r); err != nil {
+		return nil, api.decodeFailed(resp, req, newDecodeError(req.contentID, err))
 	}
+	r.req = req
 
-	response, err := api.UnmarshalReceiver(r)
+	item, err := api.UnmarshalReceiver(r)
+	if req.failure != nil {
+		return nil, req.failure
+	}
+	if err != nil {
+		return item, err
+	}
+	setResponseMeta(item, resp)
+	setReport(item, req.report)
 
-	return response, err
+	return item, nil
 }
 
-func (api *api) unmarshalArrayResponse(bytes []byte) (result []Item, err error) {
+func (api *api) unmarshalArrayResponse(resp *response, req *request) (result []Item, err error) {
+	if req.failure != nil {
+		return nil, req.failure
+	}
+	if resp.streamed {
+		for _, item := range resp.items {
+			setResponseMeta(item, resp)
+			setReport(item, req.report)
+		}
+		return resp.items, nil
+	}
+	if api.keepsRaw() {
+		// decoding object by object captures the raw JSON of each one on the way
+		if result, err = api.decodeItems(api.ownBody(resp), req); err != nil {
+			return nil, api.decodeFailed(resp, req, err)
+		}
+		if req.failure != nil {
+			return nil, req.failure
+		}
+		for _, item := range result {
+			setResponseMeta(item, resp)
+			setReport(item, req.report)
+		}
+		return result, nil
+	}
+
 	var ra []Receiver
 
-	err = json.Unmarshal(bytes, &ra)
+	err = json.Unmarshal(resp.body, &ra)
 	if err != nil {
-		return nil, err
+		return nil, api.decodeFailed(resp, req, newDecodeError(req.contentID, err))
 	}
 
-	for _, r := range ra {
+	for i, r := range ra {
+		r.req = req
+		r.at = location{index: i, indexed: true}
 		item, err := api.UnmarshalReceiver(r)
 		if err != nil {
-			log.Warn("error unmarshalling item from array: %v", err)
+			api.dropArrayItem(&r, item, err)
 		} else {
+			setResponseMeta(item, resp)
 			result = append(result, item)
 		}
+		if req.failed() {
+			return nil, req.failure
+		}
+	}
+	for _, item := range result {
+		setReport(item, req.report)
 	}
 
 	return result, err
@@ -192,7 +350,10 @@ func (api *api) unmarshalArrayResponse(bytes []byte) (result []Item, err error)
 
 func unmarshalClosingsResponse(bytes []byte) (result ClosingsResponse, err error) {
 	err = json.Unmarshal(bytes, &result)
-	return result, err
+	if err != nil {
+		return result, newDecodeError(0, err)
+	}
+	return result, nil
 }
 
 func (api *api) unmarshalClsInstitution(bytes []byte) (result ClsInstitution, err error) {
@@ -200,48 +361,117 @@ func (api *api) unmarshalClsInstitution(bytes []byte) (result ClsInstitution, er
 	return result, err
 }
 
+// unmarshalChildren unmarshals the sub-objects held in the given field of r, dropping the
+// ones that cannot be unmarshalled. Sub-objects that were already unmarshalled while the
+// response was streamed are taken as they are.
+func (api *api) unmarshalChildren(r *Receiver, field string, children []Receiver) (result []Item) {
+	if r.streamed != nil {
+		return api.keepStreamedChildren(r, field)
+	}
+
+	var parent string
+	if len(children) > 0 {
+		parent = r.at.String()
+	}
+	for i, rInner := range children {
+		rInner.req = r.req
+		rInner.at = location{parent: parent, field: field, index: i, indexed: true}
+		item, err := api.UnmarshalReceiver(rInner)
+		if api.keepChild(r, field, newDecodedChild(&rInner, item, err)) {
+			result = append(result, item)
+		}
+		if r.req.failed() {
+			break
+		}
+	}
+	return result
+}
+
+// keepStreamedChildren returns the sub-objects held in the given field of a streamed r,
+// leaving out the ones that could not be unmarshalled
+func (api *api) keepStreamedChildren(r *Receiver, field string) []Item {
+	f := r.streamed.field(field)
+	if len(f.failed) > 0 {
+		// compact in place, the failed sub-objects are only reported once
+		kept := f.items[:0]
+		for i, item := range f.items {
+			if len(f.failed) > 0 && f.failed[0].index == i {
+				api.keepChild(r, field, f.failed[0].decodedChild)
+				f.failed = f.failed[1:]
+				continue
+			}
+			kept = append(kept, item)
+		}
+		f.items = kept
+	}
+	if len(f.items) == 0 {
+		return nil
+	}
+	return f.items
+}
+
+// keepChild reports whether a sub-object of r belongs in r, recording it in the decode
+// report if it doesn't
+func (api *api) keepChild(r *Receiver, field string, child decodedChild) bool {
+	err := child.err
+	if err == nil {
+		return true
+	}
+	r.req.drop(r, field, child)
+	// a strict request fails with the first dropped sub-object, which says it all
+	if dropsQuietly(r, field, err) || r.req.failed() {
+		return false
+	}
+	api.logger.Warn("error unmarshalling sub-object", r.logFields("field", field, "error", err)...)
+	api.observeDecodeWarning(r.req)
+	return false
+}
+
+// dropArrayItem records an element of an array response that couldn't be unmarshalled,
+// warning about it unless the request fails because of it
+func (api *api) dropArrayItem(r *Receiver, item Item, err error) {
+	r.req.drop(nil, "", newDecodedChild(r, item, err))
+	if r.req.failed() {
+		return
+	}
+	api.logger.Warn("error unmarshalling item from array", r.logFields("error", err)...)
+	api.observeDecodeWarning(r.req)
+}
+
+// dropsQuietly reports whether a sub-object of r that failed with err is left out without
+// a warning. Unsupported and target-less sub-objects are expected anywhere but in the
+// items of a gallery.
+func dropsQuietly(r *Receiver, field string, err error) bool {
+	if r.Type == GalleryType && field == "items" {
+		return false
+	}
+	return err == ErrUnsupportedType || err == ErrTeaserMissingTarget
+}
+
+// UnmarshalReceiver turns r into an Item using the decoder registered for its type
 func (api *api) UnmarshalReceiver(r Receiver) (Item, error) {
-	switch r.Type {
-	case ArticleType:
-		return api.unmarshalArticle(r), nil
-	case VideoType:
-		return api.unmarshalVideo(r), nil
-	case CollectionType:
-		return api.unmarshalCollection(r), nil
-	case SearchType:
-		return api.unmarshalSearch(r), nil
-	case ImageType:
-		return unmarshalImage(r), nil
-	case GalleryType:
-		return api.unmarshalGallery(r), nil
-	case MapType:
-		return unmarshalMap(r), nil
-	case ExternalContentType:
-		return unmarshalExternalContent(r), nil
-	case ExternalLinkType:
-		return api.unmarshalExternalLink(r), nil
-	case HTMLType:
-		return unmarshalHTMLContent(r), nil
-	case PersonType:
-		return unmarshalPerson(r), nil
-	case LivevideoType:
-		return api.unmarshalLivevideo(r), nil
-	case SettingsType:
-		return unmarshalSettings(r), nil
-	case AudioType:
-		return api.unmarshalAudio(r), nil
-	case TeaserType:
-		return api.unmarshalTeaser(r)
-	case DownloadFileType:
-		return api.unmarshalDownloadFile(r), nil
-	case UnsupportedType:
-		return nil, errUnsupportedType
-	default:
-		return nil, fmt.Errorf("unknonwn response type for obj %d: %s", r.ContentID, r.Type)
-	}
-}
-
-func (api *api) unmarshalArticle(r Receiver) (a *Article) {
+	decode := api.decoderFor(r.Type)
+	if decode == nil {
+		err := fmt.Errorf("%w for obj %d: %s", ErrUnknownType, r.ContentID, r.Type)
+		if api.genericItems {
+			return genericItem(r, err), nil
+		}
+		return nil, err
+	}
+
+	item, err := decode(decoder{api}, r)
+	if err != nil && api.genericItems && isUndecodableType(err) {
+		return genericItem(r, err), nil
+	}
+	if api.rawJSON && item != nil {
+		if m := getMeta(item); m != nil {
+			m.raw = r.raw
+		}
+	}
+	return item, err
+}
+
+func unmarshalArticle(d Decoder, r Receiver) (a *Article) {
 	a = &Article{}
 	a.Type = r.Type
 	a.ContentID = r.ContentID
@@ -260,34 +490,14 @@ func (api *api) unmarshalArticle(r Receiver) (a *Article) {
 	a.AdvertisingCategory = r.AdvertisingCategory
 	a.AdvertisingCategoryPath = r.AdvertisingCategoryPath
 	a.Dateline = r.Dateline
-	for _, rInner := range r.Media {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			a.Media = append(a.Media, item)
-		}
-	}
+	a.Media = d.UnmarshalChildren(&r, "media", r.Media)
 
-	for _, rInner := range r.RelatedMedia {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling related media sub-object: %v", err)
-		} else {
-			a.RelatedMedia = append(a.RelatedMedia, item)
-		}
-	}
+	a.RelatedMedia = d.UnmarshalChildren(&r, "related_media", r.RelatedMedia)
 
 	return a
 }
 
-func (api *api) unmarshalVideo(r Receiver) (v *Video) {
+func unmarshalVideo(d Decoder, r Receiver) (v *Video) {
 	v = &Video{}
 	v.Type = r.Type
 	v.ContentID = r.ContentID
@@ -307,22 +517,12 @@ func (api *api) unmarshalVideo(r Receiver) (v *Video) {
 	v.AdvertisingCategoryPath = r.AdvertisingCategoryPath
 	v.ShowAds = r.ShowAds
 	v.Stream = r.Stream
-	for _, rInner := range r.Media {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			v.Media = append(v.Media, item)
-		}
-	}
+	v.Media = d.UnmarshalChildren(&r, "media", r.Media)
 
 	return v
 }
 
-func (api *api) unmarshalLivevideo(r Receiver) (l *Livevideo) {
+func unmarshalLivevideo(d Decoder, r Receiver) (l *Livevideo) {
 	l = &Livevideo{}
 	l.Type = r.Type
 	l.ContentID = r.ContentID
@@ -335,29 +535,19 @@ func (api *api) unmarshalLivevideo(r Receiver) (l *Livevideo) {
 	l.Title = r.Title
 	l.CanonicalURL = r.CanonicalURL
 	l.URL = r.URL
-	l.Stream = getStream(&r)
+	l.Stream = getStream(d, &r)
 	l.ExternalID = r.ExternalID
 	l.NavContext = r.NavContext
 	l.AnalyticsCategory = r.AnalyticsCategory
 	l.AdvertisingCategory = r.AdvertisingCategory
 	l.AdvertisingCategoryPath = r.AdvertisingCategoryPath
 	l.ShowAds = r.ShowAds
-	for _, rInner := range r.Media {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			l.Media = append(l.Media, item)
-		}
-	}
+	l.Media = d.UnmarshalChildren(&r, "media", r.Media)
 
 	return l
 }
 
-func getStream(r *Receiver) (stream string) {
+func getStream(d Decoder, r *Receiver) (stream string) {
 	stream = r.Stream
 	if strings.Contains(r.ExternalID, ":") == false {
 		return stream
@@ -368,7 +558,7 @@ func getStream(r *Receiver) (stream string) {
 		if len(result) > 1 {
 			stream = searchString + result[1]
 		} else {
-			log.Warn("error: nil found splitting ExternalID: %s at %s", r.ExternalID, searchString)
+			d.Logger().Warn("error: nil found splitting ExternalID", r.logFields("external_id", r.ExternalID, "separator", searchString)...)
 		}
 	}
 	return stream
@@ -399,7 +589,7 @@ func unmarshalImage(r Receiver) (i *Image) {
 	return i
 }
 
-func (api *api) unmarshalGallery(r Receiver) (g *Gallery) {
+func unmarshalGallery(d Decoder, r Receiver) (g *Gallery) {
 	g = &Gallery{}
 	g.Type = r.Type
 	g.ContentID = r.ContentID
@@ -416,25 +606,8 @@ func (api *api) unmarshalGallery(r Receiver) (g *Gallery) {
 	g.AnalyticsCategory = r.AnalyticsCategory
 	g.AdvertisingCategory = r.AdvertisingCategory
 	g.AdvertisingCategoryPath = r.AdvertisingCategoryPath
-	for _, rInner := range r.Media {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			g.Media = append(g.Media, item)
-		}
-	}
-	for _, rInner := range r.Items {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			g.Items = append(g.Items, item)
-		}
-	}
+	g.Media = d.UnmarshalChildren(&r, "media", r.Media)
+	g.Items = d.UnmarshalChildren(&r, "items", r.Items)
 
 	// if r.Captions exists, our receiver came from somewhere other than IB (i.e. a database)
 	// if r.Captions does not exist, we assume IB and try to get the captions from their looney tunes struct.
@@ -468,7 +641,7 @@ func unmarshalMap(r Receiver) (m *Map) {
 	return m
 }
 
-func (api *api) unmarshalCollection(r Receiver) (c *Collection) {
+func unmarshalCollection(d Decoder, r Receiver) (c *Collection) {
 	c = &Collection{}
 	c.Type = r.Type
 	c.ContentID = r.ContentID
@@ -477,28 +650,8 @@ func (api *api) unmarshalCollection(r Receiver) (c *Collection) {
 	c.ContentName = r.ContentName
 	c.TotalCount = r.TotalCount
 	c.StartIndex = r.StartIndex
-	for _, rInner := range r.Media {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			c.Media = append(c.Media, item)
-		}
-	}
-	for _, rInner := range r.Items {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			c.Items = append(c.Items, item)
-		}
-	}
+	c.Media = d.UnmarshalChildren(&r, "media", r.Media)
+	c.Items = d.UnmarshalChildren(&r, "items", r.Items)
 	c.Settings = r.Settings
 
 	c.NavContext = r.NavContext
@@ -509,23 +662,13 @@ func (api *api) unmarshalCollection(r Receiver) (c *Collection) {
 	return c
 }
 
-func (api *api) unmarshalSearch(r Receiver) (s *Collection) {
+func unmarshalSearch(d Decoder, r Receiver) (s *Collection) {
 	s = &Collection{}
 	s.Type = s.GetType()
 	s.Keywords = r.Keywords
 	s.TotalCount = r.TotalCount
 	s.StartIndex = r.StartIndex
-	for _, rInner := range r.Items {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			s.Items = append(s.Items, item)
-		}
-	}
+	s.Items = d.UnmarshalChildren(&r, "items", r.Items)
 
 	return s
 }
@@ -541,7 +684,7 @@ func unmarshalExternalContent(r Receiver) (e *ExternalContent) {
 	return e
 }
 
-func (api *api) unmarshalExternalLink(r Receiver) (e *ExternalLink) {
+func unmarshalExternalLink(d Decoder, r Receiver) (e *ExternalLink) {
 	e = &ExternalLink{}
 	e.Type = r.Type
 	e.ContentID = r.ContentID
@@ -550,17 +693,7 @@ func (api *api) unmarshalExternalLink(r Receiver) (e *ExternalLink) {
 	e.TeaserText = r.TeaserText
 	e.CanonicalURL = r.CanonicalURL
 	e.URL = r.URL
-	for _, rInner := range r.Media {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			e.Media = append(e.Media, item)
-		}
-	}
+	e.Media = d.UnmarshalChildren(&r, "media", r.Media)
 
 	return e
 }
@@ -620,7 +753,7 @@ func unmarshalPerson(r Receiver) (p *Person) {
 	return p
 }
 
-func (api *api) unmarshalAudio(r Receiver) (a *Audio) {
+func unmarshalAudio(d Decoder, r Receiver) (a *Audio) {
 	a = &Audio{}
 	a.Type = r.Type
 	a.ContentID = r.ContentID
@@ -637,22 +770,12 @@ func (api *api) unmarshalAudio(r Receiver) (a *Audio) {
 	a.AnalyticsCategory = r.AnalyticsCategory
 	a.AdvertisingCategory = r.AdvertisingCategory
 	a.AdvertisingCategoryPath = r.AdvertisingCategoryPath
-	for _, rInner := range r.Media {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			a.Media = append(a.Media, item)
-		}
-	}
+	a.Media = d.UnmarshalChildren(&r, "media", r.Media)
 
 	return a
 }
 
-func (api *api) unmarshalTeaser(r Receiver) (t *Teaser, err error) {
+func unmarshalTeaser(d Decoder, r Receiver) (t *Teaser, err error) {
 	t = &Teaser{}
 	t.Type = r.Type
 	t.ContentID = r.ContentID
@@ -665,32 +788,30 @@ func (api *api) unmarshalTeaser(r Receiver) (t *Teaser, err error) {
 	t.AnalyticsCategory = r.AnalyticsCategory
 	t.AdvertisingCategory = r.AdvertisingCategory
 	t.AdvertisingCategoryPath = r.AdvertisingCategoryPath
-	for _, rInner := range r.Media {
-		item, err := api.UnmarshalReceiver(rInner)
-		if err != nil {
-			if err == errUnsupportedType || err == errTeaserMissingTarget {
-				continue
-			}
-			log.Warn("error unmarshalling sub-object: %v", err)
-		} else {
-			t.Media = append(t.Media, item)
-		}
-	}
+	t.Media = d.UnmarshalChildren(&r, "media", r.Media)
 
 	if r.Target == nil {
-		return t, errTeaserMissingTarget
+		return t, ErrTeaserMissingTarget
 	}
 
-	target, err := api.UnmarshalReceiver(*r.Target)
+	rTarget := *r.Target
+	rTarget.req = r.req
+	rTarget.at = location{parent: r.at.String(), field: "target"}
+	target, err := d.UnmarshalReceiver(rTarget)
 	if err != nil {
-		return t, err
+		if r.at == (location{}) {
+			// nothing drops the teaser of a single-object response, so report the target
+			r.req.drop(&r, "target", newDecodedChild(&rTarget, target, err))
+			return t, err
+		}
+		return t, &targetError{err: err, path: rTarget.at.String()}
 	}
 	t.Target = target
 
 	return t, nil
 }
 
-func (api *api) unmarshalDownloadFile(r Receiver) (df *DownloadFile) {
+func unmarshalDownloadFile(d Decoder, r Receiver) (df *DownloadFile) {
 	df = &DownloadFile{}
 	df.Type = r.Type
 	df.ContentID = r.ContentID
@@ -1,6 +1,8 @@
 package goib
 
 import (
+	"context"
+	"errors"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -150,7 +152,7 @@ func TestShouldUnmarshallArticleMedia(t *testing.T) {
 		},
 	}
 
-	art := a.(*api).unmarshalArticle(r)
+	art := unmarshalArticle(decoder{a.(*api)}, r)
 	assert.Equal(t, 2, len(art.Media), "media should have two elements")
 }
 
@@ -294,6 +296,21 @@ func TestContentAPIShouldParseTeaserType(t *testing.T) {
 	assert.Equal(t, 1, len(tease.Media))
 }
 
+func TestContentContextShouldFailOnCanceledContext(t *testing.T) {
+	svr, a := setupServerAndAPI(imageJSON)
+	defer svr.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := a.ContentContext(ctx, "someKrazyChannel", 12345, nil)
+	assert.True(t, errors.Is(err, context.Canceled), fmt.Sprint(err))
+
+	response, err := a.ContentContext(context.Background(), "someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 29283344, response.GetContentID())
+}
+
 func Test_unmarshalGalleryCaptions(t *testing.T) {
 	var r Receiver
 
@@ -329,7 +346,7 @@ func setupServerAndAPI(cannedResponse string) (*httptest.Server, API) {
 func setupServerAndAPIWithHTTPStatus(cannedResponse string, status int) (*httptest.Server, API) {
 	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "floopy/yowza")
-		w.WriteHeader(500)
+		w.WriteHeader(status)
 		fmt.Fprintln(w, cannedResponse)
 	}))
 
@@ -344,6 +361,14 @@ func setupServerAndAPIWithHTTPStatus(cannedResponse string, status int) (*httpte
 	return testSvr, a
 }
 
+// setupServerAndAPIWithOptions starts a server answering with handler, and an API sending
+// its requests there, constructed with opts
+func setupServerAndAPIWithOptions(handler http.HandlerFunc, opts ...Option) (*httptest.Server, API) {
+	testSvr := httptest.NewServer(handler)
+	testURL, _ := url.Parse(testSvr.URL)
+	return testSvr, NewAPI(append([]Option{WithHost(testURL.Host)}, opts...)...)
+}
+
 func assertGracefulFailOnAllAPIMethods(t *testing.T, a API) {
 	_, err := a.Entry("someKrazyChannel", "someKookyKollection", nil)
 	assert.NotNil(t, err, "error should not be nil")
@@ -0,0 +1,119 @@
+package goib
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is sent with every request. Setting it ourselves turns off the
+// transparent decompression of http.Transport, so responses are decoded in readBody.
+const acceptEncoding = "gzip, deflate"
+
+// defaultMaxBodySize is the largest decoded response body accepted by default
+const defaultMaxBodySize = 64 << 20
+
+// ErrResponseTooLarge is returned when a decoded response body exceeds the maximum body size
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// WithMaxBodySize sets the largest decoded response body accepted from IB, in bytes.
+// The default is 64 MiB; zero or less removes the limit.
+func WithMaxBodySize(size int64) Option {
+	return func(a *api) {
+		a.maxBodySize = size
+	}
+}
+
+// readBody reads and decodes the body of an IB response, reading at most limit decoded bytes
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if limit <= 0 {
+		return ioutil.ReadAll(body)
+	}
+
+	result, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(result)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+	return result, nil
+}
+
+// openBody returns a reader over the decoded response body that fails with
+// ErrResponseTooLarge once more than limit bytes have been read
+func openBody(resp *http.Response, limit int64) (io.ReadCloser, error) {
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return body, nil
+	}
+	return &maxBytesReader{ReadCloser: body, r: io.LimitReader(body, limit+1), limit: limit}, nil
+}
+
+type maxBytesReader struct {
+	io.ReadCloser
+	r     io.Reader
+	read  int64
+	limit int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.read += int64(n)
+	if m.read > m.limit {
+		return n - int(m.read-m.limit), fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, m.limit)
+	}
+	return n, err
+}
+
+// readSnippet reads at most limit decoded bytes of the response body, ignoring the rest
+func readSnippet(resp *http.Response, limit int64) []byte {
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	snippet, _ := ioutil.ReadAll(io.LimitReader(body, limit))
+	return snippet
+}
+
+// decodeBody wraps the response body in a reader undoing its Content-Encoding
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return ioutil.NopCloser(resp.Body), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some servers send raw deflate streams
+		br := bufio.NewReader(resp.Body)
+		header, err := br.Peek(2)
+		if err == nil && isZlibHeader(header) {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
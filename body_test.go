@@ -0,0 +1,81 @@
+package goib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupEncodingServerAndAPI(encoding string, compress func(w io.Writer) io.WriteCloser, opts ...Option) (*httptest.Server, API, *string) {
+	var gotAcceptEncoding string
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		gotAcceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", encoding)
+		cw := compress(w)
+		io.WriteString(cw, imageJSON)
+		cw.Close()
+	}, opts...)
+
+	return testSvr, a, &gotAcceptEncoding
+}
+
+func TestShouldDecodeCompressedResponses(t *testing.T) {
+	encoders := map[string]func(w io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+	}
+
+	for encoding, compress := range encoders {
+		svr, a, gotAcceptEncoding := setupEncodingServerAndAPI(encoding, compress)
+
+		item, err := a.Content("someKrazyChannel", 12345, nil)
+		assert.Nil(t, err, encoding)
+		assert.Equal(t, 29283344, item.GetContentID(), encoding)
+		assert.Equal(t, "gzip, deflate", *gotAcceptEncoding)
+
+		svr.Close()
+	}
+}
+
+func TestShouldDecodeRawDeflateResponses(t *testing.T) {
+	svr, a, _ := setupEncodingServerAndAPI("deflate", func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	})
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 29283344, item.GetContentID())
+}
+
+func TestShouldRejectOversizedResponses(t *testing.T) {
+	svr, a, _ := setupEncodingServerAndAPI("gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, WithMaxBodySize(100))
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+}
+
+func TestReadBodyShouldAcceptBodiesUpToTheLimit(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewBufferString("12345"))}
+	body, err := readBody(resp, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("12345"), body)
+
+	resp = &http.Response{Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewBufferString("123456"))}
+	_, err = readBody(resp, 5)
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+
+	resp = &http.Response{Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewBufferString("123456"))}
+	assert.Equal(t, []byte("123"), readSnippet(resp, 3))
+}
@@ -0,0 +1,235 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting IB while the circuit breaker for the
+// requested host is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of the circuit breaker for a single IB host
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all requests fast with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen lets a single trial request through at a time
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerSettings configures the circuit breakers kept for each IB host
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a trial request is let through
+	OpenTimeout time.Duration
+	// HalfOpenSuccesses is the number of successful trial requests needed to close the circuit
+	HalfOpenSuccesses int
+	// OnStateChange, if set, is called whenever the circuit for a host changes state
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+// DefaultCircuitBreakerSettings opens the circuit after five consecutive failures
+var DefaultCircuitBreakerSettings = CircuitBreakerSettings{
+	FailureThreshold:  5,
+	OpenTimeout:       10 * time.Second,
+	HalfOpenSuccesses: 1,
+}
+
+// WithCircuitBreaker wraps requests in a circuit breaker per IB host. Transport errors
+// and 5xx responses count as failures; other responses count as successes.
+func WithCircuitBreaker(settings CircuitBreakerSettings) Option {
+	return func(a *api) {
+		a.breakers = newCircuitBreakers(settings)
+	}
+}
+
+type circuitBreakers struct {
+	settings CircuitBreakerSettings
+	now      func() time.Time
+	logger   Logger
+
+	mu     sync.Mutex
+	byHost map[string]*circuitBreaker
+}
+
+func newCircuitBreakers(settings CircuitBreakerSettings) *circuitBreakers {
+	if settings.FailureThreshold < 1 {
+		settings.FailureThreshold = 1
+	}
+	if settings.HalfOpenSuccesses < 1 {
+		settings.HalfOpenSuccesses = 1
+	}
+	return &circuitBreakers{
+		settings: settings,
+		logger:   packageLogger,
+		now:      time.Now,
+		byHost:   make(map[string]*circuitBreaker),
+	}
+}
+
+// forHost returns the circuit breaker for the given host, or nil if circuit breaking is disabled
+func (cbs *circuitBreakers) forHost(host string) *circuitBreaker {
+	if cbs == nil {
+		return nil
+	}
+
+	cbs.mu.Lock()
+	defer cbs.mu.Unlock()
+
+	cb, ok := cbs.byHost[host]
+	if !ok {
+		cb = &circuitBreaker{host: host, parent: cbs}
+		cbs.byHost[host] = cb
+	}
+	return cb
+}
+
+type circuitBreaker struct {
+	host   string
+	parent *circuitBreakers
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	successes int
+	openedAt  time.Time
+	trialing  bool
+}
+
+// State returns the current state of the circuit
+func (cb *circuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// allow reports whether a request may be sent to the host. Every allowed request must be
+// followed by a call to done.
+func (cb *circuitBreaker) allow() error {
+	if cb == nil {
+		return nil
+	}
+
+	cb.mu.Lock()
+	from := cb.state
+
+	switch cb.state {
+	case CircuitOpen:
+		if cb.parent.now().Sub(cb.openedAt) < cb.parent.settings.OpenTimeout {
+			cb.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		cb.state = CircuitHalfOpen
+		cb.successes = 0
+		cb.trialing = true
+	case CircuitHalfOpen:
+		if cb.trialing {
+			cb.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		cb.trialing = true
+	}
+
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+	return nil
+}
+
+// done records the outcome of a request let through by allow
+func (cb *circuitBreaker) done(ctx context.Context, err error) {
+	if cb == nil {
+		return
+	}
+	// a caller giving up says nothing about the health of the host
+	if err != nil && ctx.Err() != nil {
+		cb.mu.Lock()
+		cb.trialing = false
+		cb.mu.Unlock()
+		return
+	}
+
+	failed := isBackendFailure(err)
+
+	cb.mu.Lock()
+	from := cb.state
+	cb.trialing = false
+
+	switch {
+	case failed && cb.state == CircuitHalfOpen:
+		cb.open()
+	case failed:
+		cb.failures++
+		if cb.failures >= cb.parent.settings.FailureThreshold {
+			cb.open()
+		}
+	case cb.state == CircuitHalfOpen:
+		cb.successes++
+		if cb.successes >= cb.parent.settings.HalfOpenSuccesses {
+			cb.state = CircuitClosed
+			cb.failures = 0
+		}
+	default:
+		cb.failures = 0
+	}
+
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+}
+
+// open must be called with cb.mu held
+func (cb *circuitBreaker) open() {
+	cb.state = CircuitOpen
+	cb.openedAt = cb.parent.now()
+	cb.failures = 0
+	cb.successes = 0
+}
+
+func (cb *circuitBreaker) notify(from, to CircuitState) {
+	if from == to {
+		return
+	}
+	cb.parent.logger.Debug("circuit state changed", "host", cb.host, "from", from, "to", to)
+	if cb.parent.settings.OnStateChange != nil {
+		cb.parent.settings.OnStateChange(cb.host, from, to)
+	}
+}
+
+// isBackendFailure reports whether err indicates that the IB host itself is in trouble
+func isBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.StatusCode >= 500
+	}
+	var de *DecodeError
+	if errors.As(err, &de) {
+		return false
+	}
+	return !errors.Is(err, ErrResponseTooLarge)
+}
@@ -0,0 +1,123 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stateChange struct {
+	from, to CircuitState
+}
+
+func setupBreakerServerAndAPI(status *int32, calls *int32) (*httptest.Server, *api, *[]stateChange, *time.Time) {
+	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(status)))
+		fmt.Fprint(w, "I leik milk!!1")
+	}))
+
+	var changes []stateChange
+	a := NewAPI(WithCircuitBreaker(CircuitBreakerSettings{
+		FailureThreshold:  2,
+		OpenTimeout:       time.Minute,
+		HalfOpenSuccesses: 1,
+		OnStateChange: func(host string, from, to CircuitState) {
+			changes = append(changes, stateChange{from, to})
+		},
+	})).(*api)
+
+	now := time.Now()
+	a.breakers.now = func() time.Time { return now }
+
+	return testSvr, a, &changes, &now
+}
+
+func TestCircuitBreakerShouldOpenAndFailFast(t *testing.T) {
+	status, calls := int32(503), int32(0)
+	svr, a, changes, _ := setupBreakerServerAndAPI(&status, &calls)
+	defer svr.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err := a.doGet(context.Background(), &request{uri: svr.URL})
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, []stateChange{{CircuitClosed, CircuitOpen}}, *changes)
+
+	_, err := a.doGet(context.Background(), &request{uri: svr.URL})
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), calls, "open circuit should not contact IB")
+}
+
+func TestCircuitBreakerShouldCloseAfterSuccessfulTrial(t *testing.T) {
+	status, calls := int32(503), int32(0)
+	svr, a, changes, now := setupBreakerServerAndAPI(&status, &calls)
+	defer svr.Close()
+
+	a.doGet(context.Background(), &request{uri: svr.URL})
+	a.doGet(context.Background(), &request{uri: svr.URL})
+
+	*now = now.Add(2 * time.Minute)
+	atomic.StoreInt32(&status, 200)
+
+	_, err := a.doGet(context.Background(), &request{uri: svr.URL})
+	assert.Nil(t, err)
+	assert.Equal(t, []stateChange{
+		{CircuitClosed, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitClosed},
+	}, *changes)
+}
+
+func TestCircuitBreakerShouldReopenAfterFailedTrial(t *testing.T) {
+	status, calls := int32(503), int32(0)
+	svr, a, _, now := setupBreakerServerAndAPI(&status, &calls)
+	defer svr.Close()
+
+	a.doGet(context.Background(), &request{uri: svr.URL})
+	a.doGet(context.Background(), &request{uri: svr.URL})
+
+	*now = now.Add(2 * time.Minute)
+	_, err := a.doGet(context.Background(), &request{uri: svr.URL})
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrCircuitOpen))
+
+	_, err = a.doGet(context.Background(), &request{uri: svr.URL})
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestCircuitBreakerShouldIgnoreClientErrors(t *testing.T) {
+	status, calls := int32(404), int32(0)
+	svr, a, changes, _ := setupBreakerServerAndAPI(&status, &calls)
+	defer svr.Close()
+
+	for i := 0; i < 5; i++ {
+		a.doGet(context.Background(), &request{uri: svr.URL})
+	}
+	assert.Equal(t, 0, len(*changes))
+	assert.Equal(t, int32(5), calls)
+}
+
+func TestCircuitBreakerHalfOpenShouldAllowSingleTrial(t *testing.T) {
+	cbs := newCircuitBreakers(CircuitBreakerSettings{FailureThreshold: 1, OpenTimeout: time.Second})
+	now := time.Now()
+	cbs.now = func() time.Time { return now }
+	cb := cbs.forHost("ib.example.com")
+
+	assert.Nil(t, cb.allow())
+	cb.done(context.Background(), errors.New("connection reset"))
+	assert.Equal(t, CircuitOpen, cb.State())
+
+	now = now.Add(2 * time.Second)
+	assert.Nil(t, cb.allow())
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+	assert.Equal(t, ErrCircuitOpen, cb.allow(), "second trial should be rejected while the first is in flight")
+}
@@ -0,0 +1,256 @@
+package goib
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// CacheEntry is an IB response body stored in a Cache
+type CacheEntry struct {
+	Body         []byte
+	ETag         string
+	LastModified string
+	StoredAt     time.Time
+	Expires      time.Time
+}
+
+// Cache stores IB responses keyed by request URI. Implementations must be safe for
+// concurrent use and must treat entries as immutable.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// CacheConfig configures response caching. A request is cached only if it resolves
+// to a positive TTL.
+type CacheConfig struct {
+	Cache Cache
+	// TTL applies to every service without an entry in ServiceTTL
+	TTL time.Duration
+	// ServiceTTL overrides TTL per service: "entry", "search", "content" or "closings"
+	ServiceTTL map[string]time.Duration
+	// EntryTypeTTL overrides the TTL of the entry service per entry type
+	EntryTypeTTL map[string]time.Duration
+	// MaxStale is how long past its expiry an entry may still be served when refreshing
+	// it fails. Zero disables serving stale entries.
+	MaxStale time.Duration
+	// StaleWhileRevalidate serves expired entries younger than MaxStale immediately and
+	// refreshes them in the background
+	StaleWhileRevalidate bool
+}
+
+// WithCache caches responses from IB. Expired entries carrying an ETag or Last-Modified
+// header are revalidated with a conditional GET instead of being fetched again. Items
+// decoded from stale entries are marked as such, see IsStale.
+func WithCache(config CacheConfig) Option {
+	return func(a *api) {
+		if config.Cache == nil {
+			config.Cache = NewLRUCache(defaultCacheCapacity)
+		}
+		a.cache = &config
+	}
+}
+
+func (c *CacheConfig) ttl(req *request) time.Duration {
+	if req.service == "entry" {
+		if ttl, ok := c.EntryTypeTTL[req.entryType]; ok {
+			return ttl
+		}
+	}
+	if ttl, ok := c.ServiceTTL[req.service]; ok {
+		return ttl
+	}
+	return c.TTL
+}
+
+// cachedGet serves the request from the cache if possible, revalidating or refreshing
+// expired entries
+func (api *api) cachedGet(ctx context.Context, req *request) (*response, error) {
+	ttl := api.cache.ttl(req)
+	if ttl <= 0 {
+		return api.coalesce(ctx, req, func(ctx context.Context) (*response, error) {
+			return api.fetch(ctx, req, nil)
+		})
+	}
+
+	now := time.Now()
+	cached, ok := api.cache.Cache.Get(req.uri)
+	if ok && now.Before(cached.Expires) {
+		api.logger.Trace("cache hit", req.logFields()...)
+		return &response{body: cached.Body, cached: true}, nil
+	}
+
+	servableStale := ok && api.cache.MaxStale > 0 && now.Before(cached.Expires.Add(api.cache.MaxStale))
+	if servableStale && api.cache.StaleWhileRevalidate {
+		api.logger.Trace("cache stale, revalidating", req.logFields()...)
+		api.refreshInBackground(req, cached, ttl)
+		return staleResponse(cached, now), nil
+	}
+
+	resp, err := api.coalesce(ctx, req, func(ctx context.Context) (*response, error) {
+		return api.refresh(ctx, req, cached, ttl)
+	})
+	if err != nil && servableStale && ctx.Err() == nil {
+		api.logger.Debug("serving stale response after refresh failed", req.logFields("error", err)...)
+		return staleResponse(cached, now), nil
+	}
+	return resp, err
+}
+
+// refresh fetches the request from IB, conditionally if the cached entry allows it, and
+// stores the result in the cache
+func (api *api) refresh(ctx context.Context, req *request, cached *CacheEntry, ttl time.Duration) (*response, error) {
+	var conditional *CacheEntry
+	if cached != nil && (cached.ETag != "" || cached.LastModified != "") {
+		conditional = cached
+	}
+
+	resp, err := api.fetch(ctx, req, conditional)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := &CacheEntry{
+		Body:         resp.body,
+		ETag:         resp.etag,
+		LastModified: resp.lastModified,
+		StoredAt:     now,
+		Expires:      now.Add(ttl),
+	}
+	if resp.notModified {
+		entry.Body = conditional.Body
+		entry.ETag = conditional.ETag
+		entry.LastModified = conditional.LastModified
+	}
+	api.cache.Cache.Set(req.uri, entry)
+
+	return &response{body: entry.Body, cached: true}, nil
+}
+
+// refreshInBackground refreshes an expired entry unless a refresh for it is already running
+func (api *api) refreshInBackground(req *request, cached *CacheEntry, ttl time.Duration) {
+	api.refreshMu.Lock()
+	if api.refreshing == nil {
+		api.refreshing = make(map[string]struct{})
+	}
+	if _, ok := api.refreshing[req.uri]; ok {
+		api.refreshMu.Unlock()
+		return
+	}
+	api.refreshing[req.uri] = struct{}{}
+	api.refreshMu.Unlock()
+
+	go func() {
+		defer func() {
+			api.refreshMu.Lock()
+			delete(api.refreshing, req.uri)
+			api.refreshMu.Unlock()
+		}()
+
+		if _, err := api.refresh(context.Background(), req, cached, ttl); err != nil {
+			api.logger.Debug("background refresh failed", req.logFields("error", err)...)
+		}
+	}()
+}
+
+func staleResponse(cached *CacheEntry, now time.Time) *response {
+	return &response{
+		body:   cached.Body,
+		stale:  true,
+		age:    now.Sub(cached.StoredAt),
+		cached: true,
+	}
+}
+
+// decodeFailed drops the cached body of resp, which failed to decode with err, so that
+// the next call fetches it again instead of failing the same way until it expires
+func (api *api) decodeFailed(resp *response, req *request, err error) error {
+	if resp.cached {
+		api.logger.Debug("dropping undecodable response from cache", req.logFields("error", err)...)
+		api.cache.Cache.Delete(req.uri)
+	}
+	return err
+}
+
+const defaultCacheCapacity = 1000
+
+// LRUCache is an in-memory Cache that evicts the least recently used entry once it
+// holds more than its capacity
+type LRUCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewLRUCache returns an LRUCache holding at most capacity entries
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the entry stored under key, marking it as recently used
+func (c *LRUCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+// Set stores entry under key, evicting the least recently used entry if the cache is full
+func (c *LRUCache) Set(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruItem{key, entry})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Delete removes the entry stored under key
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of entries in the cache
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,231 @@
+package goib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCachingServerAndAPI(cannedResponse string, config CacheConfig, handler func(w http.ResponseWriter, r *http.Request) bool, opts ...Option) (*httptest.Server, API, *int32) {
+	var calls int32
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if handler != nil && handler(w, r) {
+			return
+		}
+		fmt.Fprintln(w, cannedResponse)
+	}, append([]Option{WithCache(config)}, opts...)...)
+
+	return testSvr, a, &calls
+}
+
+func TestCacheShouldServeRepeatedRequests(t *testing.T) {
+	svr, a, calls := setupCachingServerAndAPI(imageJSON, CacheConfig{TTL: time.Minute}, nil)
+	defer svr.Close()
+
+	first, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	second, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, int32(1), *calls)
+	assert.Equal(t, first, second)
+	assert.True(t, first != second, "cached responses should be unmarshalled into fresh items")
+
+	_, err = a.Content("someKrazyChannel", 54321, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), *calls)
+}
+
+func TestCacheShouldApplyServiceAndEntryTypeTTLs(t *testing.T) {
+	config := CacheConfig{
+		ServiceTTL:   map[string]time.Duration{"entry": time.Minute},
+		EntryTypeTTL: map[string]time.Duration{"breaking": 0},
+	}
+	svr, a, calls := setupCachingServerAndAPI(entryJSON, config, nil)
+	defer svr.Close()
+
+	a.Entry("someKrazyChannel", "homepage", nil)
+	a.Entry("someKrazyChannel", "homepage", nil)
+	assert.Equal(t, int32(1), *calls)
+
+	a.Entry("someKrazyChannel", "breaking", nil)
+	a.Entry("someKrazyChannel", "breaking", nil)
+	assert.Equal(t, int32(3), *calls)
+
+	a.Content("someKrazyChannel", 12345, nil)
+	a.Content("someKrazyChannel", 12345, nil)
+	assert.Equal(t, int32(5), *calls, "content has no TTL and should not be cached")
+}
+
+func TestCacheShouldRevalidateWithETag(t *testing.T) {
+	var conditional int32
+	svr, a, calls := setupCachingServerAndAPI(imageJSON, CacheConfig{TTL: time.Nanosecond}, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		w.Header().Set("ETag", `"v1"`)
+		return false
+	})
+	defer svr.Close()
+
+	first, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+	second, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, int32(2), *calls)
+	assert.Equal(t, int32(1), conditional)
+	assert.Equal(t, first, second)
+}
+
+func TestCacheShouldRevalidateWithLastModified(t *testing.T) {
+	lastModified := "Sat, 27 Aug 2016 12:00:00 GMT"
+	var conditional int32
+	svr, a, _ := setupCachingServerAndAPI(imageJSON, CacheConfig{TTL: time.Nanosecond}, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		return false
+	})
+	defer svr.Close()
+
+	a.Content("someKrazyChannel", 12345, nil)
+	time.Sleep(time.Millisecond)
+	item, err := a.Content("someKrazyChannel", 12345, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), conditional)
+	assert.Equal(t, 29283344, item.GetContentID())
+}
+
+func TestLRUCacheShouldEvictLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set("a", &CacheEntry{Body: []byte("a")})
+	c.Set("b", &CacheEntry{Body: []byte("b")})
+	c.Get("a")
+	c.Set("c", &CacheEntry{Body: []byte("c")})
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "b should have been evicted")
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	c.Delete("a")
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCacheShouldServeStaleOnError(t *testing.T) {
+	var failing int32
+	config := CacheConfig{TTL: time.Millisecond, MaxStale: time.Minute}
+	svr, a, _ := setupCachingServerAndAPI(imageJSON, config, func(w http.ResponseWriter, r *http.Request) bool {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	stale, _ := IsStale(item)
+	assert.False(t, stale)
+
+	atomic.StoreInt32(&failing, 1)
+	time.Sleep(5 * time.Millisecond)
+
+	item, err = a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 29283344, item.GetContentID())
+	stale, age := IsStale(item)
+	assert.True(t, stale)
+	assert.True(t, age >= 5*time.Millisecond, age.String())
+}
+
+func TestCacheShouldDropUndecodableResponses(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithRawJSON()}} {
+		var malformed int32 = 1
+		svr, a, calls := setupCachingServerAndAPI(imageJSON, CacheConfig{TTL: time.Minute, MaxStale: time.Minute},
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if atomic.CompareAndSwapInt32(&malformed, 1, 0) {
+					fmt.Fprintln(w, `{"type":"IMAGE",`)
+					return true
+				}
+				return false
+			}, opts...)
+
+		_, err := a.Content("someKrazyChannel", 12345, nil)
+		var de *DecodeError
+		assert.True(t, errors.As(err, &de))
+
+		item, err := a.Content("someKrazyChannel", 12345, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 29283344, item.GetContentID())
+		assert.Equal(t, int32(2), *calls)
+
+		svr.Close()
+	}
+}
+
+func TestCacheShouldNotServeStaleBeyondMaxStale(t *testing.T) {
+	var failing int32
+	config := CacheConfig{TTL: time.Millisecond, MaxStale: time.Millisecond}
+	svr, a, _ := setupCachingServerAndAPI(imageJSON, config, func(w http.ResponseWriter, r *http.Request) bool {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	defer svr.Close()
+
+	a.Content("someKrazyChannel", 12345, nil)
+	atomic.StoreInt32(&failing, 1)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.NotNil(t, err)
+}
+
+func TestCacheShouldServeStaleWhileRevalidating(t *testing.T) {
+	config := CacheConfig{TTL: 50 * time.Millisecond, MaxStale: time.Minute, StaleWhileRevalidate: true}
+	svr, a, calls := setupCachingServerAndAPI(imageJSON, config, nil)
+	defer svr.Close()
+
+	a.Content("someKrazyChannel", 12345, nil)
+	time.Sleep(60 * time.Millisecond)
+
+	item, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	stale, _ := IsStale(item)
+	assert.True(t, stale, "expired entry should be served immediately")
+
+	deadline := time.Now().Add(time.Second)
+	for stale && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		item, err = a.Content("someKrazyChannel", 12345, nil)
+		assert.Nil(t, err)
+		stale, _ = IsStale(item)
+	}
+	assert.False(t, stale, "entry should have been refreshed in the background")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
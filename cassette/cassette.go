@@ -0,0 +1,355 @@
+// Package cassette records the HTTP traffic between goib and IB to a directory of JSON
+// files, and replays it offline. A Recorder and a Replayer are both http.RoundTrippers,
+// meant to be passed to goib.WithTransport:
+//
+//	rec, _ := cassette.NewRecorder("testdata/cassettes", nil)
+//	api := goib.NewAPI(goib.WithTransport(rec))
+//
+//	rep, _ := cassette.NewReplayer("testdata/cassettes")
+//	api := goib.NewAPI(goib.WithTransport(rep))
+//
+// Query parameters carrying secrets, such as API keys set with goib.WithDefaultParams,
+// should be kept out of cassettes that get committed:
+//
+//	rec, _ := cassette.NewRecorder("testdata/cassettes", nil, cassette.RedactParams("api_key"))
+package cassette
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNoMatch is returned by a Replayer for requests that match no recorded interaction
+var ErrNoMatch = errors.New("no recorded interaction matches request")
+
+// Interaction is a request/response pair stored in a cassette file
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of an HTTP request
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// Response is the recorded part of an HTTP response. Compressed bodies are recorded
+// decompressed, so that cassettes can be read and edited; bodies that still aren't valid
+// UTF-8 are stored base64-encoded.
+type Response struct {
+	StatusCode   int         `json:"status_code"`
+	Status       string      `json:"status"`
+	Header       http.Header `json:"header"`
+	Body         string      `json:"body"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+func (r *Response) body() ([]byte, error) {
+	if r.BodyEncoding == "base64" {
+		return base64.StdEncoding.DecodeString(r.Body)
+	}
+	return []byte(r.Body), nil
+}
+
+// Redacted is recorded in place of the values of redacted query parameters
+const Redacted = "REDACTED"
+
+// Recorder is an http.RoundTripper that sends requests on and writes every exchange to
+// a cassette directory, one file per distinct request. Recording the same request again
+// overwrites its file.
+type Recorder struct {
+	dir    string
+	next   http.RoundTripper
+	redact []string
+	mu     sync.Mutex
+}
+
+// RecordOption configures a Recorder
+type RecordOption func(*Recorder)
+
+// RedactParams records the values of the named query parameters as Redacted, so that
+// secrets don't end up in cassette files. Replayers match redacted parameters on their
+// name only.
+func RedactParams(params ...string) RecordOption {
+	return func(r *Recorder) {
+		r.redact = append(r.redact, params...)
+	}
+}
+
+// NewRecorder constructs a Recorder writing to dir, which is created if needed. Requests
+// are sent with next, or http.DefaultTransport if nil.
+func NewRecorder(dir string, next http.RoundTripper, opts ...RecordOption) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := &Recorder{dir: dir, next: next}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	recorded := Response{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header.Clone(),
+	}
+	if decoded, ok := decompress(body, resp.Header.Get("Content-Encoding")); ok {
+		// replayed as is, the body no longer matches these headers
+		recorded.Header.Del("Content-Encoding")
+		recorded.Header.Del("Content-Length")
+		body = decoded
+	}
+	recorded.Body = string(body)
+	if !utf8.Valid(body) {
+		recorded.Body = base64.StdEncoding.EncodeToString(body)
+		recorded.BodyEncoding = "base64"
+	}
+
+	interaction := Interaction{
+		Request:  Request{Method: req.Method, URL: r.redacted(req.URL).String()},
+		Response: recorded,
+	}
+	if err = r.write(interaction); err != nil {
+		return nil, fmt.Errorf("error recording interaction: %w", err)
+	}
+
+	return resp, nil
+}
+
+// decompress decodes a body sent with the given Content-Encoding, reporting false if
+// the body isn't compressed or can't be decoded
+func decompress(body []byte, encoding string) ([]byte, bool) {
+	var rd io.ReadCloser
+	var err error
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		rd, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some servers send raw deflate streams
+		if rd, err = zlib.NewReader(bytes.NewReader(body)); err != nil {
+			rd, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return nil, false
+	}
+	if err != nil {
+		return nil, false
+	}
+	defer rd.Close()
+
+	decoded, err := ioutil.ReadAll(rd)
+	return decoded, err == nil
+}
+
+func (r *Recorder) write(interaction Interaction) error {
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(interaction.Request.URL)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ioutil.WriteFile(filepath.Join(r.dir, fileName(interaction.Request.Method, u)), data, 0644)
+}
+
+// redacted returns u with the values of the redacted query parameters replaced
+func (r *Recorder) redacted(u *url.URL) *url.URL {
+	if len(r.redact) == 0 {
+		return u
+	}
+
+	query := u.Query()
+	for _, param := range r.redact {
+		redactValues(query, param)
+	}
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return &redacted
+}
+
+// redactValues replaces every value of the given query parameter with Redacted
+func redactValues(query url.Values, param string) {
+	for i := range query[param] {
+		query[param][i] = Redacted
+	}
+}
+
+// fileName derives a readable, unique file name from a request's method, path and query
+func fileName(method string, u *url.URL) string {
+	sum := sha1.Sum([]byte(method + " " + u.Path + "?" + u.Query().Encode()))
+	path := strings.Trim(strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, u.Path), "_")
+
+	return strings.ToLower(method) + "_" + path + "_" + hex.EncodeToString(sum[:4]) + ".json"
+}
+
+// Matcher reports whether an incoming request matches a recorded one
+type Matcher func(req *http.Request, recorded Request) bool
+
+// MatchPath matches requests on method and path, ignoring the query
+func MatchPath(req *http.Request, recorded Request) bool {
+	u, err := url.Parse(recorded.URL)
+	return err == nil && req.Method == recorded.Method && req.URL.Path == u.Path
+}
+
+// MatchPathAndQuery matches requests on method, path and query parameters, regardless of
+// their order. This is the default Matcher of a Replayer.
+func MatchPathAndQuery(req *http.Request, recorded Request) bool {
+	return MatchPathAndQueryIgnoring()(req, recorded)
+}
+
+// MatchPathAndQueryIgnoring works like MatchPathAndQuery, but disregards the named query
+// parameters, e.g. cache busters. Parameters recorded as Redacted are compared in their
+// redacted form, so they match whatever value they are sent with.
+func MatchPathAndQueryIgnoring(params ...string) Matcher {
+	return func(req *http.Request, recorded Request) bool {
+		if !MatchPath(req, recorded) {
+			return false
+		}
+		u, _ := url.Parse(recorded.URL)
+		want, got := u.Query(), req.URL.Query()
+		for _, param := range params {
+			want.Del(param)
+			got.Del(param)
+		}
+		for param, values := range want {
+			if len(values) > 0 && values[0] == Redacted {
+				redactValues(got, param)
+			}
+		}
+		return reflect.DeepEqual(want, got)
+	}
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette directory
+// without touching the network. Requests that match no recorded interaction fail with
+// ErrNoMatch, unless a fallback transport is configured.
+type Replayer struct {
+	interactions []Interaction
+	match        Matcher
+	fallback     http.RoundTripper
+}
+
+// ReplayOption configures a Replayer
+type ReplayOption func(*Replayer)
+
+// WithMatcher sets the rule deciding which recorded interaction answers a request
+func WithMatcher(match Matcher) ReplayOption {
+	return func(r *Replayer) {
+		r.match = match
+	}
+}
+
+// WithFallback sends requests that match no recorded interaction with the given
+// transport instead of failing them
+func WithFallback(transport http.RoundTripper) ReplayOption {
+	return func(r *Replayer) {
+		r.fallback = transport
+	}
+}
+
+// NewReplayer constructs a Replayer serving the interactions recorded in dir
+func NewReplayer(dir string, opts ...ReplayOption) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Replayer{match: MatchPathAndQuery}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var interaction Interaction
+		if err = json.Unmarshal(data, &interaction); err != nil {
+			return nil, fmt.Errorf("error reading cassette file %s: %w", file, err)
+		}
+		r.interactions = append(r.interactions, interaction)
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, interaction := range r.interactions {
+		if r.match(req, interaction.Request) {
+			return interaction.Response.toHTTP(req)
+		}
+	}
+
+	if r.fallback != nil {
+		return r.fallback.RoundTrip(req)
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoMatch, req.Method, req.URL)
+}
+
+func (r *Response) toHTTP(req *http.Request) (*http.Response, error) {
+	body, err := r.body()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode:    r.StatusCode,
+		Status:        r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
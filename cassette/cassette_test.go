@@ -0,0 +1,179 @@
+package cassette
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Hearst-DD/goib"
+	"github.com/stretchr/testify/assert"
+)
+
+var imageJSON = `{"type":"IMAGE","content_id":29283344,"teaser_title":"Recorded image","urls":[]}`
+
+func setupServer(calls *int32, gzipped bool) (*httptest.Server, string) {
+	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if gzipped {
+			w.Header().Set("Content-Encoding", "gzip")
+			gw := gzip.NewWriter(w)
+			gw.Write([]byte(imageJSON))
+			gw.Close()
+			return
+		}
+		w.Write([]byte(imageJSON))
+	}))
+
+	testURL, _ := url.Parse(testSvr.URL)
+	return testSvr, testURL.Host
+}
+
+func TestShouldReplayRecordedInteractions(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		var calls int32
+		dir := t.TempDir()
+		svr, host := setupServer(&calls, gzipped)
+
+		rec, err := NewRecorder(dir, nil)
+		assert.Nil(t, err)
+		params := url.Values{"b": {"2"}, "a": {"1"}}
+		recorded, err := goib.NewAPI(goib.WithHost(host), goib.WithTransport(rec)).Content("wesh", 29283344, params)
+		assert.Nil(t, err)
+		svr.Close()
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		assert.Equal(t, 1, len(files))
+		var interaction Interaction
+		data, _ := ioutil.ReadFile(files[0])
+		assert.Nil(t, json.Unmarshal(data, &interaction))
+		assert.Equal(t, imageJSON, interaction.Response.Body, "bodies should be recorded readable")
+		assert.Equal(t, "", interaction.Response.BodyEncoding)
+		assert.Equal(t, "", interaction.Response.Header.Get("Content-Encoding"))
+
+		rep, err := NewReplayer(dir)
+		assert.Nil(t, err)
+		replayed, err := goib.NewAPI(goib.WithHost(host), goib.WithTransport(rep)).Content("wesh", 29283344, params)
+		assert.Nil(t, err)
+		assert.Equal(t, recorded, replayed)
+		assert.Equal(t, int32(1), calls)
+	}
+}
+
+func TestReplayerShouldFailUnmatchedRequests(t *testing.T) {
+	var calls int32
+	dir := t.TempDir()
+	svr, host := setupServer(&calls, false)
+	defer svr.Close()
+
+	rec, _ := NewRecorder(dir, nil)
+	_, err := goib.NewAPI(goib.WithHost(host), goib.WithTransport(rec)).Content("wesh", 1, nil)
+	assert.Nil(t, err)
+
+	rep, _ := NewReplayer(dir)
+	_, err = goib.NewAPI(goib.WithHost(host), goib.WithTransport(rep)).Content("wesh", 2, nil)
+	assert.True(t, errors.Is(err, ErrNoMatch))
+
+	rep, _ = NewReplayer(dir, WithFallback(http.DefaultTransport))
+	_, err = goib.NewAPI(goib.WithHost(host), goib.WithTransport(rep)).Content("wesh", 2, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestShouldRedactParamsFromCassettes(t *testing.T) {
+	var calls int32
+	dir := t.TempDir()
+	svr, host := setupServer(&calls, false)
+	defer svr.Close()
+
+	rec, _ := NewRecorder(dir, nil, RedactParams("api_key"))
+	secret := goib.WithDefaultParams(url.Values{"api_key": {"s3cr3t"}})
+	_, err := goib.NewAPI(goib.WithHost(host), goib.WithTransport(rec), secret).Content("wesh", 1, nil)
+	assert.Nil(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Equal(t, 1, len(files))
+	data, _ := ioutil.ReadFile(files[0])
+	assert.NotContains(t, string(data), "s3cr3t")
+	assert.Contains(t, string(data), "api_key="+Redacted)
+
+	// replaying matches whatever key is sent, but still requires one
+	rep, _ := NewReplayer(dir)
+	other := goib.WithDefaultParams(url.Values{"api_key": {"other"}})
+	_, err = goib.NewAPI(goib.WithHost(host), goib.WithTransport(rep), other).Content("wesh", 1, nil)
+	assert.Nil(t, err)
+	_, err = goib.NewAPI(goib.WithHost(host), goib.WithTransport(rep)).Content("wesh", 1, nil)
+	assert.True(t, errors.Is(err, ErrNoMatch))
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestMatchers(t *testing.T) {
+	recorded := Request{Method: "GET", URL: "http://ib/v2.0/delivery/wesh/json/content/1?a=1&b=2"}
+	req := func(rawURL string) *http.Request {
+		r, _ := http.NewRequest("GET", rawURL, nil)
+		return r
+	}
+
+	assert.True(t, MatchPathAndQuery(req("http://other/v2.0/delivery/wesh/json/content/1?b=2&a=1"), recorded))
+	assert.False(t, MatchPathAndQuery(req("http://ib/v2.0/delivery/wesh/json/content/1?a=1"), recorded))
+	assert.False(t, MatchPathAndQuery(req("http://ib/v2.0/delivery/wesh/json/content/2?a=1&b=2"), recorded))
+	assert.True(t, MatchPath(req("http://ib/v2.0/delivery/wesh/json/content/1"), recorded))
+	assert.True(t, MatchPathAndQueryIgnoring("b", "c")(req("http://ib/v2.0/delivery/wesh/json/content/1?a=1&c=3"), recorded))
+
+	post, _ := http.NewRequest("POST", "http://ib/v2.0/delivery/wesh/json/content/1?a=1&b=2", nil)
+	assert.False(t, MatchPath(post, recorded))
+}
+
+func TestRecorderShouldPassThroughBody(t *testing.T) {
+	var calls int32
+	svr, host := setupServer(&calls, false)
+	defer svr.Close()
+
+	rec, _ := NewRecorder(t.TempDir(), nil)
+	req, _ := http.NewRequest("GET", "http://"+host+"/anything", nil)
+	resp, err := rec.RoundTrip(req)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, imageJSON, string(body))
+}
+
+func TestDecompress(t *testing.T) {
+	var gz, zl, fl bytes.Buffer
+	for _, w := range []io.WriteCloser{gzip.NewWriter(&gz), zlib.NewWriter(&zl), flateWriter(&fl)} {
+		io.WriteString(w, imageJSON)
+		w.Close()
+	}
+
+	for _, fixture := range []struct {
+		body     []byte
+		encoding string
+	}{
+		{gz.Bytes(), "gzip"},
+		{zl.Bytes(), "deflate"},
+		{fl.Bytes(), "Deflate"},
+	} {
+		decoded, ok := decompress(fixture.body, fixture.encoding)
+		assert.True(t, ok, fixture.encoding)
+		assert.Equal(t, imageJSON, string(decoded), fixture.encoding)
+	}
+
+	_, ok := decompress([]byte(imageJSON), "")
+	assert.False(t, ok)
+	_, ok = decompress([]byte(imageJSON), "gzip")
+	assert.False(t, ok)
+}
+
+func flateWriter(w io.Writer) io.WriteCloser {
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
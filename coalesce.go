@@ -0,0 +1,87 @@
+package goib
+
+import (
+	"context"
+	"sync"
+)
+
+// WithRequestCoalescing makes concurrent calls that resolve to the same URI share a single
+// request to IB. Each caller still returns as soon as its own context is done; the shared
+// request is only cancelled once every caller waiting on it has given up.
+func WithRequestCoalescing() Option {
+	return func(a *api) {
+		a.flights = &flightGroup{logger: packageLogger, calls: make(map[string]*flight)}
+	}
+}
+
+type flightGroup struct {
+	logger Logger
+
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	resp *response
+	err  error
+}
+
+// do calls fn once for all concurrent callers using the same key. fn runs with a context
+// that keeps the values of the first caller's context but none of its cancellation.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (*response, error)) (*response, error) {
+	g.mu.Lock()
+	f, ok := g.calls[key]
+	if !ok {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = f
+
+		go func() {
+			f.resp, f.err = fn(flightCtx)
+			cancel()
+
+			g.mu.Lock()
+			g.forget(key, f)
+			g.mu.Unlock()
+			close(f.done)
+		}()
+	} else {
+		g.logger.Trace("joining in-flight request", "uri", key)
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.resp, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			g.forget(key, f)
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes the flight from the group unless it has already been replaced. It must
+// be called with g.mu held.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.calls[key] == f {
+		delete(g.calls, key)
+	}
+}
+
+// coalesce runs fn, sharing it with concurrent identical requests if coalescing is enabled
+func (api *api) coalesce(ctx context.Context, req *request, fn func(ctx context.Context) (*response, error)) (*response, error) {
+	if api.flights == nil {
+		return fn(ctx)
+	}
+	return api.flights.do(ctx, req.uri, fn)
+}
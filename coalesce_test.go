@@ -0,0 +1,105 @@
+package goib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBlockingServerAndAPI(cannedResponse string, opts ...Option) (*httptest.Server, API, chan struct{}, *int32) {
+	release := make(chan struct{})
+	var calls int32
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprintln(w, cannedResponse)
+	}, opts...)
+
+	return testSvr, a, release, &calls
+}
+
+func TestCoalescingShouldShareOneRequest(t *testing.T) {
+	svr, a, release, calls := setupBlockingServerAndAPI(imageJSON, WithRequestCoalescing())
+	defer svr.Close()
+
+	var wg sync.WaitGroup
+	items := make([]Item, 10)
+	for i := range items {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			item, err := a.Content("someKrazyChannel", 12345, nil)
+			assert.Nil(t, err)
+			items[i] = item
+		}(i)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	for _, item := range items {
+		assert.Equal(t, 29283344, item.GetContentID())
+	}
+	assert.True(t, items[0] != items[1], "each caller should get its own item")
+}
+
+func TestCoalescingWaiterShouldHonorOwnContext(t *testing.T) {
+	svr, a, release, calls := setupBlockingServerAndAPI(imageJSON, WithRequestCoalescing())
+	defer svr.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		item, err := a.Content("someKrazyChannel", 12345, nil)
+		assert.Nil(t, err)
+		assert.NotNil(t, item)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := a.ContentContext(ctx, "someKrazyChannel", 12345, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestCoalescingShouldCancelWhenAllWaitersLeave(t *testing.T) {
+	g := &flightGroup{logger: packageLogger, calls: make(map[string]*flight)}
+	cancelled := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := g.do(ctx, "key", func(ctx context.Context) (*response, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("shared request was not cancelled")
+	}
+}
@@ -0,0 +1,80 @@
+package goib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// maxErrorBodySnippet is the number of bytes of an error response kept in an HTTPError
+const maxErrorBodySnippet = 512
+
+var (
+	// ErrNotFound matches, via errors.Is, any HTTPError caused by a 404 from IB
+	ErrNotFound = errors.New("not found")
+	// ErrUnsupportedType is returned for items IB marks as UNSUPPORTED
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrTeaserMissingTarget is returned for teasers that do not carry a target item
+	ErrTeaserMissingTarget = errors.New("teaser missing target")
+	// ErrUnknownType is wrapped by the error returned for items of a type goib does not know
+	ErrUnknownType = errors.New("unknown response type")
+)
+
+// HTTPError is returned when IB responds with a status other than 200 OK
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	URL        string
+	// Body holds the beginning of the response body, which often explains the error
+	Body string
+
+	retryAfter string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("IB returned an error: %s: %s", e.Status, e.URL)
+}
+
+// Is makes errors.Is(err, ErrNotFound) true for 404 responses
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == 404
+}
+
+// DecodeError is returned when an IB response cannot be decoded
+type DecodeError struct {
+	// ContentID is the ID of the requested content, or 0 if the request was not for a single content ID.
+	// When decoding strictly, it is the ID of the sub-object that couldn't be decoded, or of
+	// the object holding it if the sub-object has none.
+	ContentID int
+	// Path is the dotted JSON path of the offending value, if known
+	Path string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	msg := "error decoding IB response"
+	if e.ContentID != 0 {
+		msg += fmt.Sprintf(" for content %d", e.ContentID)
+	}
+	if e.Path != "" {
+		msg += " at " + e.Path
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying decoding error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// newDecodeError wraps a JSON decoding error, extracting the path of the offending value
+func newDecodeError(contentID int, err error) *DecodeError {
+	de := &DecodeError{ContentID: contentID, Err: err}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		de.Path = typeErr.Field
+	}
+
+	return de
+}
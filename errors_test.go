@@ -0,0 +1,65 @@
+package goib
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldReturnHTTPErrorMatchingErrNotFound(t *testing.T) {
+	svr, a := setupServerAndAPIWithHTTPStatus(badResponseHTML, 404)
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+
+	assert.True(t, errors.Is(err, ErrNotFound))
+	var httpErr *HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 404, httpErr.StatusCode)
+	assert.Equal(t, badResponseHTML+"\n", httpErr.Body)
+}
+
+func TestHTTPErrorShouldOnlyMatchErrNotFoundFor404(t *testing.T) {
+	assert.True(t, errors.Is(&HTTPError{StatusCode: 404}, ErrNotFound))
+	assert.False(t, errors.Is(&HTTPError{StatusCode: 500}, ErrNotFound))
+}
+
+func TestShouldReturnDecodeErrorForMalformedResponse(t *testing.T) {
+	svr, a := setupServerAndAPI(missingCloseBracketJSON)
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+
+	var decodeErr *DecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, 12345, decodeErr.ContentID)
+}
+
+func TestDecodeErrorShouldCarryJSONPath(t *testing.T) {
+	svr, a := setupServerAndAPI(`{"type": "COLLECTION", "items": [{"type": "ARTICLE", "content_id": "not a number"}]}`)
+	defer svr.Close()
+
+	_, err := a.Entry("someKrazyChannel", "someKookyKollection", nil)
+
+	var decodeErr *DecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.True(t, strings.HasPrefix(decodeErr.Path, "items."), decodeErr.Path)
+	assert.True(t, strings.HasSuffix(decodeErr.Path, ".content_id"), decodeErr.Path)
+	assert.Equal(t, "error decoding IB response at "+decodeErr.Path+": "+decodeErr.Err.Error(), err.Error())
+}
+
+func TestUnmarshalReceiverShouldReturnExportedErrors(t *testing.T) {
+	a := NewAPI()
+
+	_, err := a.UnmarshalReceiver(Receiver{Type: UnsupportedType})
+	assert.Equal(t, ErrUnsupportedType, err)
+
+	_, err = a.UnmarshalReceiver(Receiver{Type: TeaserType})
+	assert.Equal(t, ErrTeaserMissingTarget, err)
+
+	_, err = a.UnmarshalReceiver(Receiver{Type: "POLL", ContentID: 42})
+	assert.True(t, errors.Is(err, ErrUnknownType))
+	assert.Equal(t, "unknown response type for obj 42: POLL", err.Error())
+}
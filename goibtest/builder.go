@@ -0,0 +1,261 @@
+package goibtest
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Hearst-DD/goib"
+)
+
+// Builder builds an IB object, along with its sub-objects, for use as a test fixture.
+// Builders render the JSON IB would send, and can be turned into the Items goib would
+// return for that JSON. Every With method modifies and returns the same Builder.
+type Builder struct {
+	r goib.Receiver
+}
+
+func newBuilder(itemType goib.ItemType, contentID int) *Builder {
+	return &Builder{r: goib.Receiver{Type: itemType, ContentID: contentID}}
+}
+
+// Collection starts a collection
+func Collection(contentID int) *Builder {
+	return newBuilder(goib.CollectionType, contentID)
+}
+
+// Article starts an article with the given title
+func Article(contentID int, title string) *Builder {
+	return newBuilder(goib.ArticleType, contentID).WithTitle(title)
+}
+
+// Video starts a video with the given title
+func Video(contentID int, title string) *Builder {
+	return newBuilder(goib.VideoType, contentID).WithTitle(title)
+}
+
+// Image starts an image served from url
+func Image(contentID int, url string) *Builder {
+	return newBuilder(goib.ImageType, contentID).WithImageURL("original", 0, 0, url)
+}
+
+// Gallery starts an image gallery with the given title
+func Gallery(contentID int, title string) *Builder {
+	return newBuilder(goib.GalleryType, contentID).WithTitle(title)
+}
+
+// Teaser starts a teaser pointing at target
+func Teaser(contentID int, target *Builder) *Builder {
+	return newBuilder(goib.TeaserType, contentID).WithTarget(target)
+}
+
+// ExternalLink starts a link to url
+func ExternalLink(contentID int, url string) *Builder {
+	b := newBuilder(goib.ExternalLinkType, contentID)
+	b.r.URL = url
+	return b
+}
+
+// Person starts a person with the given full name
+func Person(contentID int, fullName string) *Builder {
+	b := newBuilder(goib.PersonType, contentID)
+	b.r.FullName = fullName
+	return b
+}
+
+// WithTitle sets the title, and the collection name of collections
+func (b *Builder) WithTitle(title string) *Builder {
+	b.r.Title = title
+	if b.r.Type == goib.CollectionType {
+		b.r.CollectionName = title
+	}
+	return b
+}
+
+// WithTeaser sets the teaser title and text
+func (b *Builder) WithTeaser(title, text string) *Builder {
+	b.r.TeaserTitle = title
+	b.r.TeaserText = text
+	return b
+}
+
+// WithText sets the body text of an article
+func (b *Builder) WithText(text string) *Builder {
+	b.r.Text = text
+	return b
+}
+
+// WithKeywords sets the keywords
+func (b *Builder) WithKeywords(keywords string) *Builder {
+	b.r.Keywords = keywords
+	return b
+}
+
+// WithAuthor sets the author byline
+func (b *Builder) WithAuthor(author string) *Builder {
+	b.r.Author = author
+	return b
+}
+
+// WithPublicationDate sets the publication date
+func (b *Builder) WithPublicationDate(t time.Time) *Builder {
+	b.r.PublicationDate = t.Unix()
+	return b
+}
+
+// WithCanonicalURL sets the canonical URL
+func (b *Builder) WithCanonicalURL(url string) *Builder {
+	b.r.CanonicalURL = url
+	return b
+}
+
+// WithImageURL adds a rendition of an image
+func (b *Builder) WithImageURL(version string, width, height int, url string) *Builder {
+	b.r.URLs = append(b.r.URLs, goib.ImageURL{Version: version, Width: width, Height: height, URL: url})
+	return b
+}
+
+// WithItems appends sub-objects to the items of a collection or gallery
+func (b *Builder) WithItems(items ...*Builder) *Builder {
+	for _, item := range items {
+		b.r.Items = append(b.r.Items, item.Receiver())
+	}
+	return b
+}
+
+// WithMedia appends sub-objects to the media
+func (b *Builder) WithMedia(media ...*Builder) *Builder {
+	for _, m := range media {
+		b.r.Media = append(b.r.Media, m.Receiver())
+	}
+	return b
+}
+
+// WithImage appends an image to the media
+func (b *Builder) WithImage(image *Builder) *Builder {
+	return b.WithMedia(image)
+}
+
+// WithRelatedMedia appends sub-objects to the related media of an article
+func (b *Builder) WithRelatedMedia(media ...*Builder) *Builder {
+	for _, m := range media {
+		b.r.RelatedMedia = append(b.r.RelatedMedia, m.Receiver())
+	}
+	return b
+}
+
+// WithTarget sets the target of a teaser
+func (b *Builder) WithTarget(target *Builder) *Builder {
+	r := target.Receiver()
+	b.r.Target = &r
+	return b
+}
+
+// With applies fn to the underlying Receiver, for fields without a dedicated method
+func (b *Builder) With(fn func(r *goib.Receiver)) *Builder {
+	fn(&b.r)
+	return b
+}
+
+// Receiver returns the Receiver tree built so far
+func (b *Builder) Receiver() goib.Receiver {
+	return b.r
+}
+
+// MarshalJSON renders the object as IB would send it, leaving out empty fields
+func (b *Builder) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(b.r)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err = json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(prune(tree))
+}
+
+// JSON renders the object as IB would send it
+func (b *Builder) JSON() []byte {
+	data, err := b.MarshalJSON()
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// String renders the object as IB would send it
+func (b *Builder) String() string {
+	return string(b.JSON())
+}
+
+// Item unmarshals the rendered JSON like goib does for responses from IB
+func (b *Builder) Item() (goib.Item, error) {
+	var r goib.Receiver
+	if err := json.Unmarshal(b.JSON(), &r); err != nil {
+		return nil, err
+	}
+	return goib.NewAPI().UnmarshalReceiver(r)
+}
+
+// MustItem is like Item, but panics if the object cannot be unmarshalled
+func (b *Builder) MustItem() goib.Item {
+	item, err := b.Item()
+	if err != nil {
+		panic(err)
+	}
+	return item
+}
+
+// Array renders the given objects as a JSON array, as returned for content media and items
+func Array(items ...*Builder) []byte {
+	data, err := json.Marshal(items)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// prune removes empty values from an object rendered from a Receiver. The type and
+// content ID are always kept, as IB always sends them.
+func prune(tree map[string]interface{}) map[string]interface{} {
+	delete(tree, "captions") // not from IB
+	for k, v := range tree {
+		if k == "type" || k == "content_id" {
+			continue
+		}
+		switch value := v.(type) {
+		case nil:
+			delete(tree, k)
+		case string:
+			if value == "" {
+				delete(tree, k)
+			}
+		case float64:
+			if value == 0 {
+				delete(tree, k)
+			}
+		case bool:
+			if !value {
+				delete(tree, k)
+			}
+		case []interface{}:
+			if len(value) == 0 {
+				delete(tree, k)
+			}
+			for _, elem := range value {
+				if obj, ok := elem.(map[string]interface{}); ok && isReceiverField(k) {
+					prune(obj)
+				}
+			}
+		case map[string]interface{}:
+			if k == "target" {
+				prune(value)
+			}
+		}
+	}
+	return tree
+}
+
+func isReceiverField(key string) bool {
+	return key == "items" || key == "media" || key == "related_media"
+}
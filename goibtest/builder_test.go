@@ -0,0 +1,82 @@
+package goibtest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Hearst-DD/goib"
+	"github.com/stretchr/testify/assert"
+)
+
+func homeCollection() *Builder {
+	return Collection(1).WithTitle("Home").WithItems(
+		Article(2, "Storm moves in").
+			WithText("<p>Heavy rain expected</p>").
+			WithPublicationDate(time.Unix(1415642175, 0)).
+			WithImage(Image(3, "http://example.com/radar.jpg").WithImageURL("16x9", 640, 360, "http://example.com/radar-640.jpg")),
+		Video(4, "Radar loop"),
+		Teaser(5, ExternalLink(6, "http://example.com")).WithTeaser("Elsewhere", ""),
+	)
+}
+
+func TestBuilderShouldRenderIBJSON(t *testing.T) {
+	var tree map[string]interface{}
+	assert.Nil(t, json.Unmarshal(homeCollection().JSON(), &tree))
+
+	assert.Equal(t, "COLLECTION", tree["type"])
+	assert.Equal(t, float64(1), tree["content_id"])
+	assert.Equal(t, "Home", tree["collection_name"])
+	assert.NotContains(t, tree, "captions")
+	assert.NotContains(t, tree, "target")
+	assert.NotContains(t, tree, "media")
+
+	article := tree["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "ARTICLE", article["type"])
+	assert.Equal(t, float64(1415642175), article["publication_date"])
+	assert.NotContains(t, article, "show_ads")
+	image := article["media"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 2, len(image["urls"].([]interface{})))
+
+	teaser := tree["items"].([]interface{})[2].(map[string]interface{})
+	assert.Equal(t, "EXTERNAL_LINK", teaser["target"].(map[string]interface{})["type"])
+}
+
+func TestBuilderShouldRoundTripThroughUnmarshalReceiver(t *testing.T) {
+	item, err := homeCollection().Item()
+	assert.Nil(t, err)
+
+	c := item.(*goib.Collection)
+	assert.Equal(t, 3, len(c.Items))
+	article := c.Items[0].(*goib.Article)
+	assert.Equal(t, "Storm moves in", article.Title)
+	assert.Equal(t, 1, len(article.Media))
+	assert.Equal(t, "http://example.com/radar.jpg", article.Media[0].(*goib.Image).URLs[0].URL)
+	teaser := c.Items[2].(*goib.Teaser)
+	assert.Equal(t, goib.ItemType(goib.ExternalLinkType), teaser.Target.GetType())
+}
+
+func TestBuilderShouldFeedServerAndFakeAPI(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetContent("wesh", 1, homeCollection())
+	s.SetContentItems("wesh", 1, Array(Article(2, "One"), Article(3, "Two")))
+
+	fromServer, err := s.API().Content("wesh", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, homeCollection().MustItem(), fromServer)
+
+	items, err := s.API().ContentItems("wesh", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(items))
+
+	f := NewFakeAPI().AddContent(homeCollection().MustItem())
+	fromFake, err := f.Content("wesh", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, fromServer, fromFake)
+}
+
+func TestBuilderWithShouldSetArbitraryFields(t *testing.T) {
+	b := Article(1, "Title").With(func(r *goib.Receiver) { r.Subheadline = "Sub" })
+	assert.Equal(t, "Sub", b.MustItem().(*goib.Article).Subheadline)
+}
@@ -0,0 +1,368 @@
+package goibtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Hearst-DD/goib"
+)
+
+var _ goib.API = (*FakeAPI)(nil)
+
+// Call is a single call made to a FakeAPI
+type Call struct {
+	Method  string
+	Channel string
+	// Arg is the entry type, query, content ID or closings filter of the call
+	Arg    string
+	Params url.Values
+}
+
+// FakeAPI is a stateful, in-memory implementation of goib.API. Content is stored by ID
+// and served on every known channel; entries and closings are stored per channel. Calls
+// for content that doesn't exist fail with a 404 *goib.HTTPError, which matches
+// goib.ErrNotFound. FakeAPI is safe for concurrent use.
+type FakeAPI struct {
+	unmarshaller goib.API
+
+	mu       sync.Mutex
+	channels map[string]bool
+	entries  map[string]goib.Item
+	content  map[int]goib.Item
+	media    map[int][]goib.Item
+	items    map[int][]goib.Item
+	closings map[string]goib.ClosingsResponse
+	errors   map[string]error
+	calls    []Call
+}
+
+// NewFakeAPI constructs an empty FakeAPI
+func NewFakeAPI() *FakeAPI {
+	return &FakeAPI{
+		unmarshaller: goib.NewAPI(),
+		channels:     make(map[string]bool),
+		entries:      make(map[string]goib.Item),
+		content:      make(map[int]goib.Item),
+		media:        make(map[int][]goib.Item),
+		items:        make(map[int][]goib.Item),
+		closings:     make(map[string]goib.ClosingsResponse),
+		errors:       make(map[string]error),
+	}
+}
+
+// AddChannel registers a channel. Once any channel has been registered, or any entry or
+// closings response stored, calls for unknown channels fail as not found.
+func (f *FakeAPI) AddChannel(channel ...string) *FakeAPI {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range channel {
+		f.channels[c] = true
+	}
+	return f
+}
+
+// SetEntry stores the item returned by Entry for the channel and entry type
+func (f *FakeAPI) SetEntry(channel, entryType string, item goib.Item) *FakeAPI {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channels[channel] = true
+	f.entries[channel+"|"+entryType] = item
+	return f
+}
+
+// AddContent stores items by their content ID
+func (f *FakeAPI) AddContent(items ...goib.Item) *FakeAPI {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range items {
+		f.content[item.GetContentID()] = item
+	}
+	return f
+}
+
+// SetContentMedia stores the items returned by ContentMedia for contentID. Without
+// them, ContentMedia returns the media of the stored content.
+func (f *FakeAPI) SetContentMedia(contentID int, media ...goib.Item) *FakeAPI {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.media[contentID] = media
+	return f
+}
+
+// SetContentItems stores the items returned by ContentItems for contentID. Without
+// them, ContentItems returns the items of the stored collection or gallery.
+func (f *FakeAPI) SetContentItems(contentID int, items ...goib.Item) *FakeAPI {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items[contentID] = items
+	return f
+}
+
+// SetClosings stores the closings response for the channel and filter. For
+// goib.ClosingsInst, pass the provider ID as well.
+func (f *FakeAPI) SetClosings(channel string, filter goib.ClosingsFilter, resp goib.ClosingsResponse, providerID ...string) *FakeAPI {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channels[channel] = true
+	f.closings[closingsKey(channel, filter, providerID)] = resp
+	return f
+}
+
+// SetError makes every call to the named method, e.g. "Content", fail with err. A nil
+// err removes the failure.
+func (f *FakeAPI) SetError(method string, err error) *FakeAPI {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errors, method)
+	} else {
+		f.errors[method] = err
+	}
+	return f
+}
+
+// Calls returns all calls made so far, in order
+func (f *FakeAPI) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallCount returns how often the named method, e.g. "Content", was called. The Context
+// variants count as their plain counterparts.
+func (f *FakeAPI) CallCount(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, call := range f.calls {
+		if call.Method == method {
+			count++
+		}
+	}
+	return count
+}
+
+// Reset forgets all recorded calls, keeping the stored content
+func (f *FakeAPI) Reset() {
+	f.mu.Lock()
+	f.calls = nil
+	f.mu.Unlock()
+}
+
+// begin records a call and returns the error it should fail with, if any. f.mu must be held.
+func (f *FakeAPI) begin(ctx context.Context, method, channel, arg string, params url.Values) error {
+	f.calls = append(f.calls, Call{Method: method, Channel: channel, Arg: arg, Params: params})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := f.errors[method]; err != nil {
+		return err
+	}
+	if len(f.channels) > 0 && !f.channels[channel] {
+		return notFoundError(channel, method, arg)
+	}
+	return nil
+}
+
+func notFoundError(channel, method, arg string) error {
+	return &goib.HTTPError{
+		StatusCode: http.StatusNotFound,
+		Status:     "404 Not Found",
+		URL:        fmt.Sprintf("fake://%s/%s/%s", channel, strings.ToLower(method), arg),
+	}
+}
+
+func (f *FakeAPI) Entry(channel string, entrytype string, params url.Values) (goib.Item, error) {
+	return f.EntryContext(context.Background(), channel, entrytype, params)
+}
+
+func (f *FakeAPI) EntryContext(ctx context.Context, channel string, entrytype string, params url.Values) (goib.Item, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "Entry", channel, entrytype, params); err != nil {
+		return nil, err
+	}
+
+	item, ok := f.entries[channel+"|"+entrytype]
+	if !ok {
+		return nil, notFoundError(channel, "Entry", entrytype)
+	}
+	return item, nil
+}
+
+func (f *FakeAPI) Search(channel string, query string, params url.Values) (*goib.Collection, error) {
+	return f.SearchContext(context.Background(), channel, query, params)
+}
+
+// SearchContext returns a collection of the stored content whose titles, teaser,
+// keywords or text contain every word of query, ignoring case
+func (f *FakeAPI) SearchContext(ctx context.Context, channel string, query string, params url.Values) (*goib.Collection, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "Search", channel, query, params); err != nil {
+		return nil, err
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	ids := make([]int, 0, len(f.content))
+	for id, item := range f.content {
+		if matchesAll(searchText(item), words) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	result := &goib.Collection{Type: goib.CollectionType, TotalCount: len(ids)}
+	for _, id := range ids {
+		result.Items = append(result.Items, f.content[id])
+	}
+	return result, nil
+}
+
+func (f *FakeAPI) Content(channel string, contentID int, params url.Values) (goib.Item, error) {
+	return f.ContentContext(context.Background(), channel, contentID, params)
+}
+
+func (f *FakeAPI) ContentContext(ctx context.Context, channel string, contentID int, params url.Values) (goib.Item, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	arg := strconv.Itoa(contentID)
+	if err := f.begin(ctx, "Content", channel, arg, params); err != nil {
+		return nil, err
+	}
+
+	item, ok := f.content[contentID]
+	if !ok {
+		return nil, notFoundError(channel, "Content", arg)
+	}
+	return item, nil
+}
+
+func (f *FakeAPI) ContentMedia(channel string, contentID int, params url.Values) ([]goib.Item, error) {
+	return f.ContentMediaContext(context.Background(), channel, contentID, params)
+}
+
+func (f *FakeAPI) ContentMediaContext(ctx context.Context, channel string, contentID int, params url.Values) ([]goib.Item, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	arg := strconv.Itoa(contentID)
+	if err := f.begin(ctx, "ContentMedia", channel, arg, params); err != nil {
+		return nil, err
+	}
+
+	if media, ok := f.media[contentID]; ok {
+		return media, nil
+	}
+	item, ok := f.content[contentID]
+	if !ok {
+		return nil, notFoundError(channel, "ContentMedia", arg)
+	}
+	switch i := item.(type) {
+	case *goib.Article:
+		return i.Media, nil
+	case *goib.Video:
+		return i.Media, nil
+	case *goib.Gallery:
+		return i.Media, nil
+	case *goib.Collection:
+		return i.Media, nil
+	}
+	return nil, nil
+}
+
+func (f *FakeAPI) ContentItems(channel string, contentID int, params url.Values) ([]goib.Item, error) {
+	return f.ContentItemsContext(context.Background(), channel, contentID, params)
+}
+
+func (f *FakeAPI) ContentItemsContext(ctx context.Context, channel string, contentID int, params url.Values) ([]goib.Item, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	arg := strconv.Itoa(contentID)
+	if err := f.begin(ctx, "ContentItems", channel, arg, params); err != nil {
+		return nil, err
+	}
+
+	if items, ok := f.items[contentID]; ok {
+		return items, nil
+	}
+	item, ok := f.content[contentID]
+	if !ok {
+		return nil, notFoundError(channel, "ContentItems", arg)
+	}
+	switch i := item.(type) {
+	case *goib.Collection:
+		return i.Items, nil
+	case *goib.Gallery:
+		return i.Items, nil
+	}
+	return nil, nil
+}
+
+func (f *FakeAPI) Closings(channel string, filter goib.ClosingsFilter, providerID ...string) (goib.ClosingsResponse, error) {
+	return f.ClosingsContext(context.Background(), channel, filter, providerID...)
+}
+
+func (f *FakeAPI) ClosingsContext(ctx context.Context, channel string, filter goib.ClosingsFilter, providerID ...string) (goib.ClosingsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "Closings", channel, string(filter), nil); err != nil {
+		return goib.ClosingsResponse{}, err
+	}
+
+	resp, ok := f.closings[closingsKey(channel, filter, providerID)]
+	if !ok {
+		return goib.ClosingsResponse{}, notFoundError(channel, "Closings", string(filter))
+	}
+	return resp, nil
+}
+
+// UnmarshalReceiver unmarshals r like a real API does
+func (f *FakeAPI) UnmarshalReceiver(r goib.Receiver) (goib.Item, error) {
+	return f.unmarshaller.UnmarshalReceiver(r)
+}
+
+func closingsKey(channel string, filter goib.ClosingsFilter, providerID []string) string {
+	key := channel + "|" + string(filter)
+	if filter == goib.ClosingsInst && len(providerID) > 0 {
+		key += "|" + providerID[0]
+	}
+	return key
+}
+
+// searchFields are the JSON fields of an item searched by FakeAPI.Search
+var searchFields = []string{"title", "teaser_title", "teaser_text", "keywords", "caption", "article_text", "collection_name", "content_name"}
+
+// searchText returns the lowercased searchable text of item
+func searchText(item goib.Item) string {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return strings.ToLower(item.GetTeaserTitle() + " " + item.GetTeaserText())
+	}
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+
+	var text []string
+	for _, name := range searchFields {
+		var s string
+		if json.Unmarshal(fields[name], &s) == nil {
+			text = append(text, s)
+		}
+	}
+	return strings.ToLower(strings.Join(text, " "))
+}
+
+func matchesAll(text string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
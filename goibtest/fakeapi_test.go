@@ -0,0 +1,111 @@
+package goibtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Hearst-DD/goib"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupFakeAPI() *FakeAPI {
+	image := &goib.Image{Type: goib.ImageType, ContentID: 3, TeaserTitle: "Radar image"}
+	article := &goib.Article{Type: goib.ArticleType, ContentID: 1, Title: "Storm moves in", Text: "Heavy rain expected", Media: []goib.Item{image}}
+	other := &goib.Article{Type: goib.ArticleType, ContentID: 2, Title: "Traffic update", Text: "Rain slows commute"}
+	home := &goib.Collection{Type: goib.CollectionType, ContentID: 10, CollectionName: "Home", Items: []goib.Item{article, other}}
+
+	return NewFakeAPI().
+		AddChannel("wesh").
+		SetEntry("wesh", "home", home).
+		AddContent(article, other, image, home).
+		SetClosings("wesh", goib.ClosingsAll, goib.ClosingsResponse{Count: goib.ClsCount{Total: 2}})
+}
+
+func TestFakeAPIShouldServeStoredContent(t *testing.T) {
+	f := setupFakeAPI()
+
+	entry, err := f.Entry("wesh", "home", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, entry.GetContentID())
+
+	item, err := f.Content("wesh", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Storm moves in", item.(*goib.Article).Title)
+
+	media, err := f.ContentMedia("wesh", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(media))
+
+	items, err := f.ContentItems("wesh", 10, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(items))
+
+	closings, err := f.Closings("wesh", goib.ClosingsAll)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, closings.Count.Total)
+}
+
+func TestFakeAPIShouldReportMissingContent(t *testing.T) {
+	f := setupFakeAPI()
+
+	_, err := f.Content("wesh", 99, nil)
+	assert.True(t, errors.Is(err, goib.ErrNotFound))
+	_, err = f.Content("kcra", 1, nil)
+	assert.True(t, errors.Is(err, goib.ErrNotFound))
+	_, err = f.Entry("wesh", "weather", nil)
+	assert.True(t, errors.Is(err, goib.ErrNotFound))
+	_, err = f.Closings("wesh", goib.ClosingsClosed)
+	assert.True(t, errors.Is(err, goib.ErrNotFound))
+}
+
+func TestFakeAPIShouldSearchByKeywords(t *testing.T) {
+	f := setupFakeAPI()
+
+	result, err := f.Search("wesh", "rain", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.TotalCount)
+	assert.Equal(t, 1, result.Items[0].GetContentID())
+	assert.Equal(t, 2, result.Items[1].GetContentID())
+
+	result, err = f.Search("wesh", "Storm RAIN", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.Items))
+
+	result, err = f.Search("wesh", "snow", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result.Items))
+}
+
+func TestFakeAPIShouldRecordCalls(t *testing.T) {
+	f := setupFakeAPI()
+
+	f.Content("wesh", 1, nil)
+	f.ContentContext(context.Background(), "wesh", 2, nil)
+	f.Entry("wesh", "home", nil)
+
+	assert.Equal(t, 2, f.CallCount("Content"))
+	assert.Equal(t, 1, f.CallCount("Entry"))
+	assert.Equal(t, Call{Method: "Content", Channel: "wesh", Arg: "2"}, f.Calls()[1])
+
+	f.Reset()
+	assert.Equal(t, 0, len(f.Calls()))
+}
+
+func TestFakeAPIShouldInjectErrors(t *testing.T) {
+	f := setupFakeAPI()
+	errBoom := errors.New("boom")
+
+	f.SetError("Content", errBoom)
+	_, err := f.Content("wesh", 1, nil)
+	assert.Equal(t, errBoom, err)
+
+	f.SetError("Content", nil)
+	_, err = f.Content("wesh", 1, nil)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = f.ContentContext(ctx, "wesh", 1, nil)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,257 @@
+// Package goibtest provides utilities for testing code that uses goib: a fake IB
+// delivery server, an in-memory fake of the API interface and builders for IB payloads.
+package goibtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Hearst-DD/goib"
+)
+
+// AnyChannel stores content that is served on every channel without content of its own
+const AnyChannel = "*"
+
+// Server is an in-process fake of the IB delivery API. It serves the routes
+// /{version}/delivery/{channel}/json/{service}/... from an in-memory content store,
+// keyed by channel and the part of the path after "json/", e.g. "content/12345" or
+// "entry/home". Search responses are keyed by their query, e.g. "search/weather".
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	content  map[string][]byte
+	faults   []*fault
+	requests []*http.Request
+}
+
+// Fault describes a misbehaviour injected into the responses of a Server
+type Fault struct {
+	// Latency delays the response
+	Latency time.Duration
+	// Status, if set, replaces the status of the response
+	Status int
+	// Body, if set, replaces the body of the response, e.g. with malformed JSON
+	Body string
+	// Times limits how many responses are affected; 0 means all of them
+	Times int
+}
+
+type fault struct {
+	Fault
+	match func(r *http.Request) bool
+	used  int
+}
+
+// NewServer starts a Server with an empty content store. Callers should Close it when done.
+func NewServer() *Server {
+	s := &Server{content: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Host returns the host:port the server listens on, as accepted by goib.WithHost
+func (s *Server) Host() string {
+	u, _ := url.Parse(s.URL)
+	return u.Host
+}
+
+// API constructs a goib.API talking to this server
+func (s *Server) API(opts ...goib.Option) goib.API {
+	return goib.NewAPI(append([]goib.Option{goib.WithHost(s.Host())}, opts...)...)
+}
+
+// Set stores body under the given channel and path, e.g. Set("wesh", "content/12345", body).
+// body may be a string, a []byte or any value that marshals to the desired JSON.
+func (s *Server) Set(channel, path string, body interface{}) error {
+	data, err := encode(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.content[key(channel, path)] = data
+	s.mu.Unlock()
+	return nil
+}
+
+// SetEntry stores the response of the entry service for entryType
+func (s *Server) SetEntry(channel, entryType string, body interface{}) error {
+	return s.Set(channel, "entry/"+entryType, body)
+}
+
+// SetSearch stores the response of the search service for query
+func (s *Server) SetSearch(channel, query string, body interface{}) error {
+	return s.Set(channel, "search/"+query, body)
+}
+
+// SetContent stores the response of the content service for contentID
+func (s *Server) SetContent(channel string, contentID int, body interface{}) error {
+	return s.Set(channel, "content/"+strconv.Itoa(contentID), body)
+}
+
+// SetContentMedia stores the media array returned for contentID
+func (s *Server) SetContentMedia(channel string, contentID int, body interface{}) error {
+	return s.Set(channel, "content/"+strconv.Itoa(contentID)+"/media", body)
+}
+
+// SetContentItems stores the items array returned for contentID
+func (s *Server) SetContentItems(channel string, contentID int, body interface{}) error {
+	return s.Set(channel, "content/"+strconv.Itoa(contentID)+"/items", body)
+}
+
+// SetClosings stores the response of the closings service for filter. For
+// goib.ClosingsInst, pass the provider ID as well.
+func (s *Server) SetClosings(channel string, filter goib.ClosingsFilter, body interface{}, providerID ...string) error {
+	path := "closings/" + string(filter)
+	if filter == goib.ClosingsInst && len(providerID) > 0 {
+		path += "/id/" + providerID[0]
+	}
+	return s.Set(channel, path, body)
+}
+
+// LoadFixtures stores every .json file below dir, which is laid out as
+// {channel}/{path}.json, e.g. wesh/content/12345.json or wesh/entry/home.json
+func (s *Server) LoadFixtures(dir string) error {
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(file) != ".json" {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, strings.TrimSuffix(file, ".json"))
+		if err != nil {
+			return err
+		}
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("fixture %s is not in a channel directory", file)
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return s.Set(parts[0], parts[1], data)
+	})
+}
+
+// Inject makes responses to requests accepted by match misbehave as described by f.
+// A nil match applies to every request. Faults are checked in the order they were injected.
+func (s *Server) Inject(match func(r *http.Request) bool, f Fault) {
+	if match == nil {
+		match = func(r *http.Request) bool { return true }
+	}
+
+	s.mu.Lock()
+	s.faults = append(s.faults, &fault{Fault: f, match: match})
+	s.mu.Unlock()
+}
+
+// ClearFaults removes all injected faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	s.faults = nil
+	s.mu.Unlock()
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	f := s.nextFault(r)
+	s.mu.Unlock()
+
+	status, body := s.lookup(r)
+	if f != nil {
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if f.Status != 0 {
+			status = f.Status
+		}
+		if f.Body != "" {
+			body = []byte(f.Body)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// nextFault returns the first fault that applies to r, if any. s.mu must be held.
+func (s *Server) nextFault(r *http.Request) *fault {
+	for _, f := range s.faults {
+		if f.Times > 0 && f.used >= f.Times {
+			continue
+		}
+		if f.match(r) {
+			f.used++
+			return f
+		}
+	}
+	return nil
+}
+
+// lookup finds the stored response for r
+func (s *Server) lookup(r *http.Request) (int, []byte) {
+	// /{version}/delivery/{channel}/json/{service}/...
+	parts := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 5)
+	if len(parts) < 5 || parts[1] != "delivery" || parts[3] != "json" {
+		return http.StatusNotFound, notFound(r)
+	}
+	channel, path := parts[2], parts[4]
+	if path == "search" {
+		path += "/" + r.URL.Query().Get("q")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data, ok := s.content[key(channel, path)]; ok {
+		return http.StatusOK, data
+	}
+	if data, ok := s.content[key(AnyChannel, path)]; ok {
+		return http.StatusOK, data
+	}
+	return http.StatusNotFound, notFound(r)
+}
+
+func notFound(r *http.Request) []byte {
+	data, _ := json.Marshal(map[string]string{"error": "not found", "path": r.URL.Path})
+	return data
+}
+
+func key(channel, path string) string {
+	return channel + "|" + strings.Trim(path, "/")
+}
+
+func encode(body interface{}) ([]byte, error) {
+	switch b := body.(type) {
+	case string:
+		return []byte(b), nil
+	case []byte:
+		return b, nil
+	default:
+		return json.Marshal(body)
+	}
+}
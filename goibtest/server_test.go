@@ -0,0 +1,109 @@
+package goibtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Hearst-DD/goib"
+	"github.com/stretchr/testify/assert"
+)
+
+const articleJSON = `{"type":"ARTICLE","content_id":42,"title":"An article","article_text":"<p>Text</p>"}`
+
+func TestServerShouldServeAllRoutes(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	assert.Nil(t, s.SetEntry("wesh", "home", `{"type":"COLLECTION","content_id":1,"items":[`+articleJSON+`]}`))
+	assert.Nil(t, s.SetSearch("wesh", "weather", `{"type":"COLLECTION","content_id":2}`))
+	assert.Nil(t, s.SetContent("wesh", 42, articleJSON))
+	assert.Nil(t, s.SetContentMedia("wesh", 42, "["+articleJSON+"]"))
+	assert.Nil(t, s.SetContentItems("wesh", 42, []byte("["+articleJSON+","+articleJSON+"]")))
+	assert.Nil(t, s.SetClosings("wesh", goib.ClosingsAll, map[string]interface{}{"closings": []interface{}{}}))
+	a := s.API()
+
+	entry, err := a.Entry("wesh", "home", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entry.(*goib.Collection).Items))
+
+	search, err := a.Search("wesh", "weather", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, search.ContentID)
+
+	content, err := a.Content("wesh", 42, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "An article", content.(*goib.Article).Title)
+
+	media, err := a.ContentMedia("wesh", 42, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(media))
+
+	items, err := a.ContentItems("wesh", 42, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(items))
+
+	_, err = a.Closings("wesh", goib.ClosingsAll)
+	assert.Nil(t, err)
+
+	_, err = a.Content("wesh", 43, nil)
+	assert.True(t, errors.Is(err, goib.ErrNotFound))
+	_, err = a.Content("kcra", 42, nil)
+	assert.True(t, errors.Is(err, goib.ErrNotFound))
+
+	assert.Equal(t, 8, len(s.Requests()))
+}
+
+func TestServerShouldServeAnyChannel(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetContent(AnyChannel, 42, articleJSON)
+
+	item, err := s.API().Content("kcra", 42, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 42, item.GetContentID())
+}
+
+func TestServerShouldLoadFixtures(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	assert.Nil(t, s.LoadFixtures("testdata"))
+	a := s.API()
+
+	item, err := a.Content("wesh", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Fixture image", item.GetTeaserTitle())
+
+	entry, err := a.Entry("wesh", "home", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Home", entry.(*goib.Collection).CollectionName)
+}
+
+func TestServerShouldInjectFaults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetContent("wesh", 42, articleJSON)
+	a := s.API()
+
+	s.Inject(nil, Fault{Status: http.StatusServiceUnavailable, Times: 1})
+	_, err := a.Content("wesh", 42, nil)
+	var he *goib.HTTPError
+	assert.True(t, errors.As(err, &he))
+	assert.Equal(t, http.StatusServiceUnavailable, he.StatusCode)
+	_, err = a.Content("wesh", 42, nil)
+	assert.Nil(t, err)
+
+	s.Inject(func(r *http.Request) bool { return r.URL.Path == "/v2.0/delivery/wesh/json/content/42" }, Fault{Body: `{"type":`})
+	_, err = a.Content("wesh", 42, nil)
+	var de *goib.DecodeError
+	assert.True(t, errors.As(err, &de))
+
+	s.ClearFaults()
+	s.Inject(nil, Fault{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = a.ContentContext(ctx, "wesh", 42, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
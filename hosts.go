@@ -0,0 +1,295 @@
+package goib
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FailoverSettings configures how an API created by NewAPIWithHosts fails over between hosts
+type FailoverSettings struct {
+	// FailureThreshold is the number of consecutive failures after which a host is marked unhealthy
+	FailureThreshold int
+	// ProbeInterval is how often unhealthy hosts are probed to see whether they recovered
+	ProbeInterval time.Duration
+	// ProbePath is the path requested by health probes. Any response below 500 counts as healthy.
+	ProbePath string
+	// OnFailover, if set, is called whenever the active host changes
+	OnFailover func(from, to string)
+}
+
+// DefaultFailoverSettings marks a host unhealthy after three consecutive failures
+var DefaultFailoverSettings = FailoverSettings{
+	FailureThreshold: 3,
+	ProbeInterval:    5 * time.Second,
+	ProbePath:        "/",
+}
+
+// HostReporter exposes which IB host an API currently sends requests to. It is
+// implemented by every API returned by this package.
+type HostReporter interface {
+	// ActiveHost returns the host new requests are sent to
+	ActiveHost() string
+	// HostHealth returns the health of every configured host
+	HostHealth() map[string]bool
+}
+
+// NewAPIWithHosts constructs an API object that sends requests to the first healthy host
+// of the given ordered list. A request failing on one host is transparently retried on
+// the next healthy one, and hosts that keep failing are marked unhealthy and probed in
+// the background until they recover. Use a type assertion to HostReporter to find out
+// which host is active, and one to io.Closer to stop the probes once the API is no
+// longer needed.
+func NewAPIWithHosts(hosts []string, opts ...Option) API {
+	if len(hosts) == 0 {
+		return NewAPI(opts...)
+	}
+
+	a := NewAPI(append([]Option{WithHost(hosts[0])}, opts...)...).(*api)
+	settings := DefaultFailoverSettings
+	if a.failover != nil {
+		settings = *a.failover
+	}
+	a.hosts = newHostPool(a, hosts, settings)
+
+	return a
+}
+
+// WithFailover configures failover for APIs created by NewAPIWithHosts
+func WithFailover(settings FailoverSettings) Option {
+	return func(a *api) {
+		a.failover = &settings
+	}
+}
+
+// ActiveHost returns the host new requests are sent to
+func (api *api) ActiveHost() string {
+	if api.hosts == nil {
+		return api.host
+	}
+	return api.hosts.active()
+}
+
+// HostHealth returns the health of every configured host
+func (api *api) HostHealth() map[string]bool {
+	if api.hosts == nil {
+		return map[string]bool{api.host: true}
+	}
+	return api.hosts.health()
+}
+
+// Close stops the background health probes of an API created by NewAPIWithHosts and
+// waits for them to finish. Requests can still be made afterwards, but unhealthy hosts
+// are no longer probed. Close never fails.
+func (api *api) Close() error {
+	if api.hosts != nil {
+		api.hosts.close()
+	}
+	return nil
+}
+
+type hostPool struct {
+	api      *api
+	settings FailoverSettings
+
+	mu      sync.Mutex
+	hosts   []*hostState
+	current string
+	probing bool
+
+	// ctx is cancelled by close to stop the probe loop, which wg waits for
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type hostState struct {
+	name     string
+	healthy  bool
+	failures int
+}
+
+func newHostPool(a *api, hosts []string, settings FailoverSettings) *hostPool {
+	if settings.FailureThreshold < 1 {
+		settings.FailureThreshold = 1
+	}
+	if settings.ProbeInterval <= 0 {
+		settings.ProbeInterval = DefaultFailoverSettings.ProbeInterval
+	}
+
+	p := &hostPool{api: a, settings: settings, current: hosts[0]}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	for _, host := range hosts {
+		p.hosts = append(p.hosts, &hostState{name: host, healthy: true})
+	}
+	return p
+}
+
+// candidates returns the healthy hosts in order of preference, or all hosts if none is healthy
+func (p *hostPool) candidates() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.candidatesLocked()
+}
+
+func (p *hostPool) candidatesLocked() (result []string) {
+	for _, h := range p.hosts {
+		if h.healthy {
+			result = append(result, h.name)
+		}
+	}
+	if len(result) == 0 {
+		for _, h := range p.hosts {
+			result = append(result, h.name)
+		}
+	}
+	return result
+}
+
+func (p *hostPool) active() string {
+	return p.candidates()[0]
+}
+
+func (p *hostPool) health() map[string]bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make(map[string]bool, len(p.hosts))
+	for _, h := range p.hosts {
+		result[h.name] = h.healthy
+	}
+	return result
+}
+
+// record tracks the outcome of a request sent to host
+func (p *hostPool) record(host string, err error) {
+	failed := isBackendFailure(err) && err != ErrCircuitOpen
+
+	p.mu.Lock()
+	for _, h := range p.hosts {
+		if h.name != host {
+			continue
+		}
+		if !failed {
+			h.failures = 0
+			break
+		}
+		h.failures++
+		if h.healthy && h.failures >= p.settings.FailureThreshold {
+			p.api.logger.Warn("marking IB host unhealthy", "host", host, "failures", h.failures, "error", err)
+			h.healthy = false
+			p.startProbingLocked()
+		}
+	}
+	p.mu.Unlock()
+
+	p.checkFailover()
+}
+
+func (p *hostPool) setHealthy(host string) {
+	p.mu.Lock()
+	for _, h := range p.hosts {
+		if h.name == host && !h.healthy {
+			p.api.logger.Warn("IB host recovered", "host", host)
+			h.healthy = true
+			h.failures = 0
+		}
+	}
+	p.mu.Unlock()
+
+	p.checkFailover()
+}
+
+// checkFailover notifies about a change of the active host
+func (p *hostPool) checkFailover() {
+	p.mu.Lock()
+	from, to := p.current, p.candidatesLocked()[0]
+	p.current = to
+	p.mu.Unlock()
+
+	if from == to {
+		return
+	}
+	p.api.logger.Warn("failing over to another IB host", "from", from, "to", to)
+	if p.settings.OnFailover != nil {
+		p.settings.OnFailover(from, to)
+	}
+}
+
+// startProbingLocked starts the health probe loop unless it is already running or the
+// pool was closed. It must be called with p.mu held.
+func (p *hostPool) startProbingLocked() {
+	if p.probing || p.ctx.Err() != nil {
+		return
+	}
+	p.probing = true
+	p.wg.Add(1)
+	go p.probeLoop()
+}
+
+// close stops the probe loop and waits for it to exit
+func (p *hostPool) close() {
+	// cancel under the lock so that no loop is started once Wait runs
+	p.mu.Lock()
+	p.cancel()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// probeLoop probes unhealthy hosts until all of them have recovered or the pool is closed
+func (p *hostPool) probeLoop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.settings.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.ctx.Done():
+			p.mu.Lock()
+			p.probing = false
+			p.mu.Unlock()
+			return
+		}
+
+		p.mu.Lock()
+		var unhealthy []string
+		for _, h := range p.hosts {
+			if !h.healthy {
+				unhealthy = append(unhealthy, h.name)
+			}
+		}
+		if len(unhealthy) == 0 {
+			p.probing = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		for _, host := range unhealthy {
+			if p.probe(host) {
+				p.setHealthy(host)
+			}
+		}
+	}
+}
+
+func (p *hostPool) probe(host string) bool {
+	ctx, cancel := context.WithTimeout(p.ctx, p.settings.ProbeInterval)
+	defer cancel()
+
+	url := p.api.scheme + "://" + host + p.settings.ProbePath
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.api.client.Do(req)
+	if err != nil {
+		p.api.logger.Debug("health probe for IB host failed", "host", host, "error", err)
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode < 500
+}
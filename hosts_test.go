@@ -0,0 +1,148 @@
+package goib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupFlakyServer(down *int32) (*httptest.Server, string) {
+	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(down) == 1 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, imageJSON)
+	}))
+
+	testURL, _ := url.Parse(testSvr.URL)
+	return testSvr, testURL.Host
+}
+
+func TestNewAPIWithHostsShouldFailOverAndRecover(t *testing.T) {
+	primaryDown, secondaryDown := int32(1), int32(0)
+	primary, primaryHost := setupFlakyServer(&primaryDown)
+	defer primary.Close()
+	secondary, secondaryHost := setupFlakyServer(&secondaryDown)
+	defer secondary.Close()
+
+	var mu sync.Mutex
+	var failovers []string
+	a := NewAPIWithHosts([]string{primaryHost, secondaryHost}, WithFailover(FailoverSettings{
+		FailureThreshold: 1,
+		ProbeInterval:    5 * time.Millisecond,
+		ProbePath:        "/",
+		OnFailover: func(from, to string) {
+			mu.Lock()
+			failovers = append(failovers, to)
+			mu.Unlock()
+		},
+	}))
+	reporter := a.(HostReporter)
+	assert.Equal(t, primaryHost, reporter.ActiveHost())
+
+	item, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err, "request should have failed over to the secondary host")
+	assert.Equal(t, 29283344, item.GetContentID())
+	assert.Equal(t, secondaryHost, reporter.ActiveHost())
+	assert.Equal(t, map[string]bool{primaryHost: false, secondaryHost: true}, reporter.HostHealth())
+
+	atomic.StoreInt32(&primaryDown, 0)
+	deadline := time.Now().Add(time.Second)
+	for reporter.ActiveHost() != primaryHost && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, primaryHost, reporter.ActiveHost(), "primary should be active again once probes succeed")
+
+	mu.Lock()
+	assert.Equal(t, []string{secondaryHost, primaryHost}, failovers)
+	mu.Unlock()
+}
+
+func TestCloseShouldStopHealthProbes(t *testing.T) {
+	var probes int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	primaryURL, _ := url.Parse(primary.URL)
+	secondary, secondaryHost := setupFlakyServer(new(int32))
+	defer secondary.Close()
+
+	a := NewAPIWithHosts([]string{primaryURL.Host, secondaryHost}, WithFailover(FailoverSettings{
+		FailureThreshold: 1,
+		ProbeInterval:    time.Millisecond,
+		ProbePath:        "/",
+	})).(*api)
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&probes) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Nil(t, a.Close())
+	a.hosts.mu.Lock()
+	assert.False(t, a.hosts.probing, "probe loop should have exited")
+	a.hosts.mu.Unlock()
+
+	// a probe cancelled by Close may still reach the server, so let it land first
+	time.Sleep(10 * time.Millisecond)
+	seen := atomic.LoadInt32(&probes)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, seen, atomic.LoadInt32(&probes), "no probes should be sent after Close")
+
+	// requests still work, but don't restart probing
+	_, err = a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	a.hosts.mu.Lock()
+	assert.False(t, a.hosts.probing)
+	a.hosts.mu.Unlock()
+	assert.Nil(t, a.Close())
+}
+
+func TestNewAPIWithHostsShouldFailWhenAllHostsAreDown(t *testing.T) {
+	down := int32(1)
+	primary, primaryHost := setupFlakyServer(&down)
+	defer primary.Close()
+	secondary, secondaryHost := setupFlakyServer(&down)
+	defer secondary.Close()
+
+	a := NewAPIWithHosts([]string{primaryHost, secondaryHost}, WithFailover(FailoverSettings{FailureThreshold: 2, ProbeInterval: time.Hour}))
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, primaryHost, a.(HostReporter).ActiveHost(), "hosts should stay healthy below the failure threshold")
+}
+
+func TestNewAPIWithHostsShouldNotFailOverOnClientErrors(t *testing.T) {
+	secondaryDown := int32(0)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer primary.Close()
+	secondary, secondaryHost := setupFlakyServer(&secondaryDown)
+	defer secondary.Close()
+
+	primaryURL, _ := url.Parse(primary.URL)
+	a := NewAPIWithHosts([]string{primaryURL.Host, secondaryHost})
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, primaryURL.Host, a.(HostReporter).ActiveHost())
+}
+
+func TestSingleHostAPIShouldReportActiveHost(t *testing.T) {
+	a := NewAPIWithHost("ib.example.com")
+	assert.Equal(t, "ib.example.com", a.(HostReporter).ActiveHost())
+}
@@ -0,0 +1,125 @@
+package goib
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	l5g "github.com/neocortical/log5go"
+)
+
+// Logger receives the log output of an API instance. fields holds alternating keys and
+// values, e.g. "channel", "wesh", "content_id", 12345. Implementations must be safe for
+// concurrent use.
+type Logger interface {
+	Trace(msg string, fields ...interface{})
+	Debug(msg string, fields ...interface{})
+	Warn(msg string, fields ...interface{})
+	Error(msg string, fields ...interface{})
+}
+
+// WithLogger sets the Logger of a single API instance. By default, APIs log through the
+// package-level logger set by SetLog.
+func WithLogger(l Logger) Option {
+	return func(a *api) {
+		a.logger = l
+	}
+}
+
+var (
+	logMu sync.RWMutex
+	log   = l5g.Logger(l5g.LogAll)
+)
+
+// SetLog sets the package-level logger. It is safe to call while APIs are logging.
+func SetLog(newLog l5g.Log5Go) {
+	logMu.Lock()
+	log = newLog
+	logMu.Unlock()
+}
+
+func packageLog() l5g.Log5Go {
+	logMu.RLock()
+	defer logMu.RUnlock()
+	return log
+}
+
+// packageLogger logs through the package-level logger
+var packageLogger Logger = log5goLogger{packageLog}
+
+// NewLog5GoLogger adapts a log5go logger to Logger. Fields are appended to the message
+// as key=value pairs.
+func NewLog5GoLogger(l l5g.Log5Go) Logger {
+	return log5goLogger{func() l5g.Log5Go { return l }}
+}
+
+type log5goLogger struct {
+	log func() l5g.Log5Go
+}
+
+func (l log5goLogger) Trace(msg string, fields ...interface{}) {
+	l.log().Trace("%s", formatFields(msg, fields))
+}
+
+func (l log5goLogger) Debug(msg string, fields ...interface{}) {
+	l.log().Debug("%s", formatFields(msg, fields))
+}
+
+func (l log5goLogger) Warn(msg string, fields ...interface{}) {
+	l.log().Warn("%s", formatFields(msg, fields))
+}
+
+func (l log5goLogger) Error(msg string, fields ...interface{}) {
+	l.log().Error("%s", formatFields(msg, fields))
+}
+
+// formatFields appends fields to msg as key=value pairs
+func formatFields(msg string, fields []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(fields); i += 2 {
+		var value interface{} = "(missing)"
+		if i+1 < len(fields) {
+			value = fields[i+1]
+		}
+		fmt.Fprintf(&b, " %v=%v", fields[i], value)
+	}
+	return b.String()
+}
+
+// setupLogger hands the API's logger to the parts that log on their own
+func (api *api) setupLogger() {
+	if api.logger == nil {
+		api.logger = packageLogger
+	}
+	if api.breakers != nil {
+		api.breakers.logger = api.logger
+	}
+	if api.limiters != nil {
+		api.limiters.logger = api.logger
+	}
+	if api.flights != nil {
+		api.flights.logger = api.logger
+	}
+}
+
+// logFields returns the fields identifying req, followed by kv
+func (req *request) logFields(kv ...interface{}) []interface{} {
+	fields := make([]interface{}, 0, 6+len(kv))
+	fields = append(fields, "channel", req.channel)
+	if req.contentID != 0 {
+		fields = append(fields, "content_id", req.contentID)
+	}
+	fields = append(fields, "uri", req.uri)
+	return append(fields, kv...)
+}
+
+// logFields returns the fields identifying r and the request it was decoded from, followed by kv
+func (r *Receiver) logFields(kv ...interface{}) []interface{} {
+	fields := make([]interface{}, 0, 8+len(kv))
+	if r.req != nil {
+		fields = append(fields, "channel", r.req.channel, "uri", r.req.uri)
+	}
+	fields = append(fields, "content_id", r.ContentID, "item_type", r.Type)
+	return append(fields, kv...)
+}
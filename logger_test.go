@@ -0,0 +1,164 @@
+package goib
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) record(level, msg string, fields []interface{}) {
+	entry := logEntry{level: level, msg: msg, fields: make(map[string]interface{})}
+	for i := 0; i+1 < len(fields); i += 2 {
+		entry.fields[fmt.Sprint(fields[i])] = fields[i+1]
+	}
+	l.mu.Lock()
+	l.entries = append(l.entries, entry)
+	l.mu.Unlock()
+}
+
+func (l *recordingLogger) Trace(msg string, fields ...interface{}) { l.record("trace", msg, fields) }
+func (l *recordingLogger) Debug(msg string, fields ...interface{}) { l.record("debug", msg, fields) }
+func (l *recordingLogger) Warn(msg string, fields ...interface{})  { l.record("warn", msg, fields) }
+func (l *recordingLogger) Error(msg string, fields ...interface{}) { l.record("error", msg, fields) }
+
+func (l *recordingLogger) warnings() (result []logEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, entry := range l.entries {
+		if entry.level == "warn" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+func setupLoggingServerAndAPI(cannedResponse string, opts ...Option) (*httptest.Server, API, *recordingLogger) {
+	logger := &recordingLogger{}
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cannedResponse))
+	}, append([]Option{WithLogger(logger)}, opts...)...)
+
+	return testSvr, a, logger
+}
+
+func TestShouldLogSubObjectWarningsWithFields(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		var opts []Option
+		if streaming {
+			opts = append(opts, WithStreamingDecode())
+		}
+		svr, a, logger := setupLoggingServerAndAPI(collectionWithUnknownItemJSON, opts...)
+
+		_, err := a.Content("someKrazyChannel", 1, nil)
+		assert.Nil(t, err)
+
+		warnings := logger.warnings()
+		assert.Equal(t, 1, len(warnings))
+		assert.Equal(t, "error unmarshalling sub-object", warnings[0].msg)
+		assert.Equal(t, "someKrazyChannel", warnings[0].fields["channel"])
+		assert.Equal(t, 1, warnings[0].fields["content_id"])
+		assert.Equal(t, CollectionType, warnings[0].fields["item_type"])
+		assert.Equal(t, "items", warnings[0].fields["field"])
+		assert.Contains(t, warnings[0].fields["uri"], "/content/1")
+		assert.NotNil(t, warnings[0].fields["error"])
+
+		svr.Close()
+	}
+}
+
+func TestShouldWarnAboutUnsupportedGalleryItems(t *testing.T) {
+	gallery := `{"type":"GALLERY","content_id":1,"media":[{"type":"UNSUPPORTED"}],"items":[{"type":"UNSUPPORTED"},` + imageJSON + `]}`
+	for _, streaming := range []bool{false, true} {
+		var opts []Option
+		if streaming {
+			opts = append(opts, WithStreamingDecode())
+		}
+		svr, a, logger := setupLoggingServerAndAPI(gallery, opts...)
+
+		item, err := a.Content("someKrazyChannel", 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(item.(*Gallery).Items))
+
+		// only the gallery item is warned about, unsupported media are expected
+		warnings := logger.warnings()
+		assert.Equal(t, 1, len(warnings))
+		assert.Equal(t, "items", warnings[0].fields["field"])
+		assert.Equal(t, ErrUnsupportedType, warnings[0].fields["error"])
+
+		svr.Close()
+	}
+}
+
+func TestShouldWarnWhenStreamingIsTurnedOff(t *testing.T) {
+	logger := &recordingLogger{}
+	NewAPI(WithLogger(logger), WithStreamingDecode(), WithCache(CacheConfig{}), WithRequestCoalescing())
+
+	warnings := logger.warnings()
+	assert.Equal(t, 1, len(warnings))
+	assert.Equal(t, "WithCache, WithRequestCoalescing", warnings[0].fields["options"])
+
+	logger = &recordingLogger{}
+	NewAPI(WithLogger(logger), WithStreamingDecode(), WithRawJSON())
+	assert.Equal(t, 0, len(logger.warnings()))
+}
+
+func TestAPIInstancesShouldLogSeparately(t *testing.T) {
+	svr1, a1, logger1 := setupLoggingServerAndAPI(collectionWithUnknownItemJSON)
+	defer svr1.Close()
+	svr2, a2, logger2 := setupLoggingServerAndAPI(imageJSON)
+	defer svr2.Close()
+
+	_, err := a1.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	_, err = a2.Content("someKrazyChannel", 2, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(logger1.warnings()))
+	assert.Equal(t, 0, len(logger2.warnings()))
+	assert.True(t, len(logger2.entries) > 0)
+}
+
+func TestSetLogShouldNotRaceWithLogging(t *testing.T) {
+	svr, _, _ := setupLoggingServerAndAPI(collectionWithUnknownItemJSON)
+	defer svr.Close()
+	testURL, _ := url.Parse(svr.URL)
+	a := NewAPIWithHost(testURL.Host)
+	original := packageLog()
+	defer SetLog(original)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.Content("someKrazyChannel", 1, nil)
+		}()
+		go func() {
+			defer wg.Done()
+			SetLog(original)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFormatFields(t *testing.T) {
+	assert.Equal(t, "msg", formatFields("msg", nil))
+	assert.Equal(t, "msg a=1 b=two", formatFields("msg", []interface{}{"a", 1, "b", "two"}))
+	assert.Equal(t, "msg a=(missing)", formatFields("msg", []interface{}{"a"}))
+}
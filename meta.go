@@ -0,0 +1,48 @@
+package goib
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// itemMeta holds information about how an item was obtained that is not part of the
+// IB payload. It is embedded in every item type.
+type itemMeta struct {
+	stale  bool
+	age    time.Duration
+	raw    json.RawMessage
+	report *DecodeReport
+}
+
+func (m *itemMeta) getMeta() *itemMeta {
+	return m
+}
+
+type metaCarrier interface {
+	getMeta() *itemMeta
+}
+
+// getMeta returns the metadata of an item, or nil for items not created by goib
+func getMeta(item Item) *itemMeta {
+	if mc, ok := item.(metaCarrier); ok {
+		return mc.getMeta()
+	}
+	return nil
+}
+
+// IsStale reports whether the item was served from an expired cache entry, and if so
+// how long ago that entry was fetched from IB
+func IsStale(item Item) (stale bool, age time.Duration) {
+	if m := getMeta(item); m != nil {
+		return m.stale, m.age
+	}
+	return false, 0
+}
+
+// setResponseMeta copies the cache status of a response onto a decoded item
+func setResponseMeta(item Item, resp *response) {
+	if m := getMeta(item); m != nil {
+		m.stale = resp.stale
+		m.age = resp.age
+	}
+}
@@ -0,0 +1,250 @@
+package goib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of the calls made to IB. Every measurement is labelled
+// with the IB service ("entry", "search", "content" or "closings") and the channel.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest is called once for every HTTP request sent to IB, including retries
+	ObserveRequest(m RequestMetric)
+	// ObserveDecodeWarning is called for every object dropped with a warning while
+	// unmarshalling a response. UNSUPPORTED and target-less sub-objects, which are expected
+	// outside galleries, are dropped without one; Report lists every dropped object.
+	ObserveDecodeWarning(service, channel string)
+}
+
+// RequestMetric describes a single HTTP request sent to IB
+type RequestMetric struct {
+	Service  string
+	Channel  string
+	Status   int // 0 if no response was received
+	Duration time.Duration
+	Bytes    int64 // decoded response body bytes read
+	Err      error
+}
+
+// WithMetrics reports request and decode measurements to the given Metrics
+func WithMetrics(m Metrics) Option {
+	return func(a *api) {
+		a.metrics = m
+	}
+}
+
+func (api *api) observeRequest(req *request, duration time.Duration, resp *response, err error) {
+	if api.metrics == nil {
+		return
+	}
+
+	m := RequestMetric{Service: req.service, Channel: req.channel, Duration: duration, Err: err}
+	var he *HTTPError
+	switch {
+	case resp != nil && resp.notModified:
+		m.Status = http.StatusNotModified
+	case resp != nil:
+		m.Status = http.StatusOK
+		m.Bytes = int64(len(resp.body)) + resp.size
+	case errors.As(err, &he):
+		m.Status = he.StatusCode
+	}
+	api.metrics.ObserveRequest(m)
+}
+
+func (api *api) observeDecodeWarning(req *request) {
+	if api.metrics == nil || req == nil {
+		return
+	}
+	api.metrics.ObserveDecodeWarning(req.service, req.channel)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the request duration
+// histogram kept by PrometheusMetrics
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a Metrics implementation that keeps its measurements in memory and
+// serves them in the Prometheus text exposition format. It is an http.Handler, so it can
+// be mounted on any existing server, e.g. at /metrics.
+type PrometheusMetrics struct {
+	namespace string
+	buckets   []float64
+
+	mu     sync.Mutex
+	series map[[2]string]*series
+}
+
+// series holds the measurements for a single service and channel
+type series struct {
+	requests map[string]uint64 // by status
+	buckets  []uint64
+	sum      float64
+	count    uint64
+	bytes    int64
+	warnings uint64
+}
+
+// NewPrometheusMetrics constructs a PrometheusMetrics whose metric names start with the
+// given namespace, "goib" if empty. Nil buckets default to DefaultDurationBuckets.
+func NewPrometheusMetrics(namespace string, buckets []float64) *PrometheusMetrics {
+	if namespace == "" {
+		namespace = "goib"
+	}
+	if buckets == nil {
+		buckets = DefaultDurationBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &PrometheusMetrics{
+		namespace: namespace,
+		buckets:   buckets,
+		series:    make(map[[2]string]*series),
+	}
+}
+
+// forLabels returns the series for service and channel, creating it if needed. The caller
+// must hold p.mu.
+func (p *PrometheusMetrics) forLabels(service, channel string) *series {
+	s := p.series[[2]string{service, channel}]
+	if s == nil {
+		s = &series{requests: make(map[string]uint64), buckets: make([]uint64, len(p.buckets))}
+		p.series[[2]string{service, channel}] = s
+	}
+	return s
+}
+
+// ObserveRequest implements Metrics
+func (p *PrometheusMetrics) ObserveRequest(m RequestMetric) {
+	status := "error"
+	if m.Status != 0 {
+		status = strconv.Itoa(m.Status)
+	}
+	seconds := m.Duration.Seconds()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.forLabels(m.Service, m.Channel)
+	s.requests[status]++
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			s.buckets[i]++
+		}
+	}
+	s.sum += seconds
+	s.count++
+	s.bytes += m.Bytes
+}
+
+// ObserveDecodeWarning implements Metrics
+func (p *PrometheusMetrics) ObserveDecodeWarning(service, channel string) {
+	p.mu.Lock()
+	p.forLabels(service, channel).warnings++
+	p.mu.Unlock()
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes all metrics to w in the Prometheus text exposition format
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	p.mu.Lock()
+	keys := make([][2]string, 0, len(p.series))
+	for k := range p.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+
+	name := p.namespace + "_requests_total"
+	fmt.Fprintf(&b, "# HELP %s Requests sent to IB.\n# TYPE %s counter\n", name, name)
+	for _, k := range keys {
+		s := p.series[k]
+		statuses := make([]string, 0, len(s.requests))
+		for status := range s.requests {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "%s{%s,status=%s} %d\n", name, labels(k), quoteLabel(status), s.requests[status])
+		}
+	}
+
+	name = p.namespace + "_request_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Duration of requests sent to IB.\n# TYPE %s histogram\n", name, name)
+	for _, k := range keys {
+		s := p.series[k]
+		if s.count == 0 {
+			continue
+		}
+		for i, bound := range p.buckets {
+			fmt.Fprintf(&b, "%s_bucket{%s,le=%s} %d\n", name, labels(k), quoteLabel(formatFloat(bound)), s.buckets[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels(k), s.count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", name, labels(k), formatFloat(s.sum))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", name, labels(k), s.count)
+	}
+
+	name = p.namespace + "_response_bytes_total"
+	fmt.Fprintf(&b, "# HELP %s Decoded response body bytes received from IB.\n# TYPE %s counter\n", name, name)
+	for _, k := range keys {
+		if s := p.series[k]; s.count > 0 {
+			fmt.Fprintf(&b, "%s{%s} %d\n", name, labels(k), s.bytes)
+		}
+	}
+
+	name = p.namespace + "_decode_warnings_total"
+	fmt.Fprintf(&b, "# HELP %s Objects dropped with a warning while unmarshalling IB responses.\n# TYPE %s counter\n", name, name)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s{%s} %d\n", name, labels(k), p.series[k].warnings)
+	}
+	p.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// labels returns the service and channel labels of a series
+func labels(k [2]string) string {
+	return "service=" + quoteLabel(k[0]) + ",channel=" + quoteLabel(k[1])
+}
+
+// labelEscaper escapes the characters the text exposition format doesn't allow in label
+// values. Go's %q would also escape non-ASCII and control characters, which Prometheus
+// reads back verbatim.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
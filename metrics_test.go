@@ -0,0 +1,129 @@
+package goib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []RequestMetric
+	warnings []string
+}
+
+func (m *recordingMetrics) ObserveRequest(rm RequestMetric) {
+	m.mu.Lock()
+	m.requests = append(m.requests, rm)
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) ObserveDecodeWarning(service, channel string) {
+	m.mu.Lock()
+	m.warnings = append(m.warnings, service+"/"+channel)
+	m.mu.Unlock()
+}
+
+func setupMetricsServerAndAPI(cannedResponse string, status int, opts ...Option) (*httptest.Server, API, *recordingMetrics) {
+	metrics := &recordingMetrics{}
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(cannedResponse))
+	}, append([]Option{WithMetrics(metrics)}, opts...)...)
+
+	return testSvr, a, metrics
+}
+
+var collectionWithUnknownItemJSON = `{"type":"COLLECTION","content_id":1,"items":[{"type":"POLL","content_id":2},` + imageJSON + `]}`
+
+func TestShouldReportRequestMetrics(t *testing.T) {
+	svr, a, metrics := setupMetricsServerAndAPI(imageJSON, http.StatusOK)
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(metrics.requests))
+	m := metrics.requests[0]
+	assert.Equal(t, "content", m.Service)
+	assert.Equal(t, "someKrazyChannel", m.Channel)
+	assert.Equal(t, http.StatusOK, m.Status)
+	assert.Equal(t, int64(len(imageJSON)), m.Bytes)
+	assert.True(t, m.Duration > 0)
+	assert.Nil(t, m.Err)
+}
+
+func TestShouldReportStatusOfFailedRequests(t *testing.T) {
+	svr, a, metrics := setupMetricsServerAndAPI(badResponseHTML, http.StatusNotFound)
+	defer svr.Close()
+
+	_, err := a.Entry("someKrazyChannel", "home", nil)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, 1, len(metrics.requests))
+	assert.Equal(t, "entry", metrics.requests[0].Service)
+	assert.Equal(t, http.StatusNotFound, metrics.requests[0].Status)
+	assert.NotNil(t, metrics.requests[0].Err)
+}
+
+func TestShouldReportDecodeWarnings(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		var opts []Option
+		if streaming {
+			opts = append(opts, WithStreamingDecode())
+		}
+		svr, a, metrics := setupMetricsServerAndAPI(collectionWithUnknownItemJSON, http.StatusOK, opts...)
+
+		item, err := a.Content("someKrazyChannel", 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(item.(*Collection).Items))
+		assert.Equal(t, []string{"content/someKrazyChannel"}, metrics.warnings)
+		assert.Equal(t, int64(len(collectionWithUnknownItemJSON)), metrics.requests[0].Bytes)
+
+		svr.Close()
+	}
+}
+
+func TestPrometheusMetricsShouldWriteTextExposition(t *testing.T) {
+	p := NewPrometheusMetrics("", []float64{0.1, 1})
+	p.ObserveRequest(RequestMetric{Service: "content", Channel: "wesh", Status: 200, Duration: 50 * time.Millisecond, Bytes: 100})
+	p.ObserveRequest(RequestMetric{Service: "content", Channel: "wesh", Status: 200, Duration: 500 * time.Millisecond, Bytes: 20})
+	p.ObserveRequest(RequestMetric{Service: "content", Channel: "wesh", Duration: time.Second})
+	p.ObserveDecodeWarning("content", "wesh")
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	for _, line := range []string{
+		"# TYPE goib_requests_total counter",
+		`goib_requests_total{service="content",channel="wesh",status="200"} 2`,
+		`goib_requests_total{service="content",channel="wesh",status="error"} 1`,
+		"# TYPE goib_request_duration_seconds histogram",
+		`goib_request_duration_seconds_bucket{service="content",channel="wesh",le="0.1"} 1`,
+		`goib_request_duration_seconds_bucket{service="content",channel="wesh",le="1"} 3`,
+		`goib_request_duration_seconds_bucket{service="content",channel="wesh",le="+Inf"} 3`,
+		`goib_request_duration_seconds_sum{service="content",channel="wesh"} 1.55`,
+		`goib_request_duration_seconds_count{service="content",channel="wesh"} 3`,
+		`goib_response_bytes_total{service="content",channel="wesh"} 120`,
+		`goib_decode_warnings_total{service="content",channel="wesh"} 1`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
+
+func TestPrometheusMetricsShouldEscapeOnlyWhatTheFormatRequires(t *testing.T) {
+	p := NewPrometheusMetrics("", nil)
+	p.ObserveDecodeWarning("content", "münchen \\ \"süd\"\nneu\t")
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Contains(t, rec.Body.String(), `goib_decode_warnings_total{service="content",channel="münchen \\ \"süd\"\nneu`+"\t"+`"} 1`+"\n")
+}
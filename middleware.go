@@ -0,0 +1,128 @@
+package goib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Middleware wraps the RoundTripper that sends requests to IB. A middleware may inspect or
+// modify a copy of the request, short-circuit it, or inspect the response.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts an ordinary function to http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Use registers middlewares around the transport used for requests to IB. Middlewares run
+// in the order they are registered, so the first one sees the request first and the
+// response last. Use may be passed to NewAPI several times.
+func Use(mw ...Middleware) Option {
+	return func(a *api) {
+		a.middleware = append(a.middleware, mw...)
+	}
+}
+
+// chainMiddleware wraps transport in the given middlewares, the first being outermost
+func chainMiddleware(transport http.RoundTripper, mw []Middleware) http.RoundTripper {
+	for i := len(mw) - 1; i >= 0; i-- {
+		transport = mw[i](transport)
+	}
+	return transport
+}
+
+// LoggingMiddleware logs every request to IB along with its outcome and duration through
+// the given logger, which would usually be the one passed to WithLogger. A nil logger logs
+// through the package-level logger.
+func LoggingMiddleware(logger Logger) Middleware {
+	if logger == nil {
+		logger = packageLogger
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				logger.Debug("request to IB failed", "method", req.Method, "url", req.URL, "duration", time.Since(start), "error", err)
+				return resp, err
+			}
+			logger.Debug("request to IB finished", "method", req.Method, "url", req.URL, "status", resp.StatusCode, "duration", time.Since(start))
+			return resp, err
+		})
+	}
+}
+
+// DefaultRequestIDHeader is the header set by RequestIDMiddleware when none is given
+const DefaultRequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context that makes RequestIDMiddleware send the given ID
+// instead of generating one
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored by ContextWithRequestID, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// RequestIDMiddleware sets a request ID header on every request to IB that doesn't have one
+// yet. The ID is taken from the request context if it was set with ContextWithRequestID, and
+// generated otherwise. An empty header defaults to DefaultRequestIDHeader and a nil generate
+// func to random 16-byte hex IDs.
+func RequestIDMiddleware(header string, generate func() string) Middleware {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	if generate == nil {
+		generate = newRequestID
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) != "" {
+				return next.RoundTrip(req)
+			}
+
+			id, ok := RequestIDFromContext(req.Context())
+			if !ok {
+				id = generate()
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set(header, id)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// HeaderMiddleware sets the given headers on every request to IB, replacing any values
+// already present
+func HeaderMiddleware(headers http.Header) Middleware {
+	headers = headers.Clone()
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for k, v := range headers {
+				req.Header[k] = append([]string(nil), v...)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
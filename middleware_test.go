@@ -0,0 +1,127 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupHeaderServerAndAPI(opts ...Option) (*httptest.Server, API, *http.Header) {
+	var gotHeader http.Header
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		w.Write([]byte(imageJSON))
+	}, opts...)
+
+	return testSvr, a, &gotHeader
+}
+
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name+" before")
+			resp, err := next.RoundTrip(req)
+			*calls = append(*calls, name+" after")
+			return resp, err
+		})
+	}
+}
+
+func TestMiddlewaresShouldRunInRegistrationOrder(t *testing.T) {
+	var calls []string
+	svr, a, _ := setupHeaderServerAndAPI(
+		Use(recordingMiddleware("first", &calls), recordingMiddleware("second", &calls)),
+		Use(recordingMiddleware("third", &calls)),
+	)
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first before", "second before", "third before", "third after", "second after", "first after"}, calls)
+}
+
+func TestMiddlewareShouldWrapConfiguredTransport(t *testing.T) {
+	var calls []string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls = append(calls, "transport")
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	svr, a, _ := setupHeaderServerAndAPI(WithTransport(transport), Use(recordingMiddleware("mw", &calls)))
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mw before", "transport", "mw after"}, calls)
+	assert.True(t, netClient.Transport == http.RoundTripper(netTransport))
+}
+
+func TestMiddlewareShouldShortCircuitRequests(t *testing.T) {
+	errInjected := errors.New("injected fault")
+	svr, a, _ := setupHeaderServerAndAPI(Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errInjected
+		})
+	}))
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.True(t, errors.Is(err, errInjected))
+}
+
+func TestHeaderMiddlewareShouldSetHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("X-Api-Key", "secret")
+	svr, a, gotHeader := setupHeaderServerAndAPI(Use(HeaderMiddleware(headers)))
+	defer svr.Close()
+	headers.Set("X-Api-Key", "changed")
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", gotHeader.Get("X-Api-Key"))
+}
+
+func TestRequestIDMiddlewareShouldGenerateIDs(t *testing.T) {
+	svr, a, gotHeader := setupHeaderServerAndAPI(Use(RequestIDMiddleware("", nil)))
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	first := gotHeader.Get(DefaultRequestIDHeader)
+	assert.Equal(t, 32, len(first))
+
+	_, err = a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, gotHeader.Get(DefaultRequestIDHeader))
+}
+
+func TestRequestIDMiddlewareShouldPreferContextID(t *testing.T) {
+	svr, a, gotHeader := setupHeaderServerAndAPI(Use(RequestIDMiddleware("X-Trace", func() string { return "generated" })))
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "generated", gotHeader.Get("X-Trace"))
+
+	ctx := ContextWithRequestID(context.Background(), "abc123")
+	_, err = a.ContentContext(ctx, "someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc123", gotHeader.Get("X-Trace"))
+}
+
+func TestLoggingMiddlewareShouldPassThrough(t *testing.T) {
+	logger := &recordingLogger{}
+	svr, a, _ := setupHeaderServerAndAPI(Use(LoggingMiddleware(logger)))
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 29283344, item.GetContentID())
+
+	assert.Equal(t, 1, len(logger.entries))
+	assert.Equal(t, "request to IB finished", logger.entries[0].msg)
+	assert.Equal(t, 200, logger.entries[0].fields["status"])
+}
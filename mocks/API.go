@@ -3,6 +3,7 @@ package mocks
 import "github.com/Hearst-DD/goib"
 import "github.com/stretchr/testify/mock"
 
+import "context"
 import "net/url"
 
 type API struct {
@@ -161,3 +162,135 @@ func (_m *API) UnmarshalReceiver(r goib.Receiver) (goib.Item, error) {
 
 	return r0, r1
 }
+
+// EntryContext provides a mock function with given fields: ctx, channel, entrytype, params
+func (_m *API) EntryContext(ctx context.Context, channel string, entrytype string, params url.Values) (goib.Item, error) {
+	ret := _m.Called(ctx, channel, entrytype, params)
+
+	var r0 goib.Item
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, url.Values) goib.Item); ok {
+		r0 = rf(ctx, channel, entrytype, params)
+	} else {
+		r0 = ret.Get(0).(goib.Item)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, url.Values) error); ok {
+		r1 = rf(ctx, channel, entrytype, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchContext provides a mock function with given fields: ctx, channel, query, params
+func (_m *API) SearchContext(ctx context.Context, channel string, query string, params url.Values) (*goib.Collection, error) {
+	ret := _m.Called(ctx, channel, query, params)
+
+	var r0 *goib.Collection
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, url.Values) *goib.Collection); ok {
+		r0 = rf(ctx, channel, query, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*goib.Collection)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, url.Values) error); ok {
+		r1 = rf(ctx, channel, query, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ContentContext provides a mock function with given fields: ctx, channel, contentID, params
+func (_m *API) ContentContext(ctx context.Context, channel string, contentID int, params url.Values) (goib.Item, error) {
+	ret := _m.Called(ctx, channel, contentID, params)
+
+	var r0 goib.Item
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, url.Values) goib.Item); ok {
+		r0 = rf(ctx, channel, contentID, params)
+	} else {
+		r0 = ret.Get(0).(goib.Item)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, url.Values) error); ok {
+		r1 = rf(ctx, channel, contentID, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ContentMediaContext provides a mock function with given fields: ctx, channel, contentID, params
+func (_m *API) ContentMediaContext(ctx context.Context, channel string, contentID int, params url.Values) ([]goib.Item, error) {
+	ret := _m.Called(ctx, channel, contentID, params)
+
+	var r0 []goib.Item
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, url.Values) []goib.Item); ok {
+		r0 = rf(ctx, channel, contentID, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]goib.Item)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, url.Values) error); ok {
+		r1 = rf(ctx, channel, contentID, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ContentItemsContext provides a mock function with given fields: ctx, channel, contentID, params
+func (_m *API) ContentItemsContext(ctx context.Context, channel string, contentID int, params url.Values) ([]goib.Item, error) {
+	ret := _m.Called(ctx, channel, contentID, params)
+
+	var r0 []goib.Item
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, url.Values) []goib.Item); ok {
+		r0 = rf(ctx, channel, contentID, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]goib.Item)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, url.Values) error); ok {
+		r1 = rf(ctx, channel, contentID, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClosingsContext provides a mock function with given fields: ctx, channel, filter, providerID
+func (_m *API) ClosingsContext(ctx context.Context, channel string, filter goib.ClosingsFilter, providerID ...string) (goib.ClosingsResponse, error) {
+	ret := _m.Called(ctx, channel, filter, providerID)
+
+	var r0 goib.ClosingsResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, goib.ClosingsFilter, ...string) goib.ClosingsResponse); ok {
+		r0 = rf(ctx, channel, filter, providerID...)
+	} else {
+		r0 = ret.Get(0).(goib.ClosingsResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, goib.ClosingsFilter, ...string) error); ok {
+		r1 = rf(ctx, channel, filter, providerID...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -1,6 +1,6 @@
 package goib
 
-import "errors"
+import "encoding/json"
 
 // ItemType is the type of content encapsulated by the object
 type ItemType string
@@ -53,9 +53,6 @@ const (
 	ClosingsInst   ClosingsFilter = "institution"
 )
 
-var errUnsupportedType = errors.New("unsupported type")
-var errTeaserMissingTarget = errors.New("teaser missing target")
-
 // Receiver captures a type-agnostic representation of an API response as a
 // step in processing a response. Its fields are a superset of all content fields,
 // so any type can be captured and derived from this struct.
@@ -110,6 +107,15 @@ type Receiver struct {
 	Target                  *Receiver           `json:"target"`
 	Captions                map[string]string   `json:"captions"` // not from IB, but needed for UnmarshalReceiver()
 	LinkText                string              `json:"link_text"`
+
+	// streamed holds the sub-objects that were unmarshalled while streaming the response
+	streamed *streamedChildren
+	// req is the call whose response this was decoded from, if any
+	req *request
+	// at locates this within the response, e.g. "items.3.target"
+	at location
+	// raw is the JSON this was decoded from, kept only when an option needs it
+	raw json.RawMessage
 }
 
 // Item is the base type of all items. It is not used outside the IB package, as
@@ -124,6 +130,8 @@ type Item interface {
 
 // Collection represents a collection of IB Items and metadata about those items
 type Collection struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType            `json:"type"`
 	ContentID               int                 `json:"content_id"`
 	TeaserTitle             string              `json:"teaser_title"`
@@ -165,6 +173,8 @@ func (c *Collection) GetPublicationDate() int64 {
 
 // Article represents an IB article
 type Article struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType `json:"type"`
 	ContentID               int      `json:"content_id"`
 	TeaserTitle             string   `json:"teaser_title"`
@@ -208,6 +218,8 @@ func (a *Article) GetPublicationDate() int64 {
 
 // Video represents an IB video
 type Video struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType      `json:"type"`
 	ContentID               int           `json:"content_id"`
 	TeaserTitle             string        `json:"teaser_title"`
@@ -263,6 +275,8 @@ type VideoFlavor struct {
 
 // Image represents an IB image content piece
 type Image struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType          `json:"type"`
 	ContentID               int               `json:"content_id"`
 	TeaserTitle             string            `json:"teaser_title"`
@@ -318,6 +332,8 @@ type ImageURL struct {
 
 // Gallery represents an image gallery
 type Gallery struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType          `json:"type"`
 	ContentID               int               `json:"content_id"`
 	TeaserTitle             string            `json:"teaser_title"`
@@ -361,6 +377,8 @@ func (g *Gallery) GetPublicationDate() int64 {
 
 // Audio represents an audio clip
 type Audio struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType `json:"type"`
 	ContentID               int      `json:"content_id"`
 	Title                   string   `json:"title"`
@@ -401,6 +419,8 @@ func (a *Audio) GetPublicationDate() int64 {
 
 // Livevideo represents a live stream
 type Livevideo struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType `json:"type"`
 	ContentID               int      `json:"content_id"`
 	Title                   string   `json:"title"`
@@ -443,6 +463,8 @@ func (l *Livevideo) GetPublicationDate() int64 {
 
 // Map represents a map
 type Map struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType `json:"type"`
 	ContentID               int      `json:"content_id"`
 	PublicationDate         int64    `json:"publication_date"`
@@ -482,6 +504,8 @@ func (m *Map) GetPublicationDate() int64 {
 
 // ExternalContent represents an external content object
 type ExternalContent struct {
+	itemMeta `json:"-"`
+
 	Type            ItemType      `json:"type"`
 	ContentID       int           `json:"content_id"`
 	PublicationDate int64         `json:"publication_date"`
@@ -512,6 +536,8 @@ func (e *ExternalContent) GetPublicationDate() int64 {
 
 // ExternalLink represents an external link object
 type ExternalLink struct {
+	itemMeta `json:"-"`
+
 	Type            ItemType `json:"type"`
 	ContentID       int      `json:"content_id"`
 	PublicationDate int64    `json:"publication_date"`
@@ -544,6 +570,8 @@ func (e *ExternalLink) GetPublicationDate() int64 {
 
 // HTMLContent represents a content object that contains a raw HTML payload
 type HTMLContent struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType `json:"type"`
 	ContentID               int      `json:"content_id"`
 	PublicationDate         int64    `json:"publication_date"`
@@ -578,6 +606,8 @@ func (h *HTMLContent) GetPublicationDate() int64 {
 
 // Person represents an IB person
 type Person struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType `json:"type"`
 	ContentID               int      `json:"content_id"`
 	Blurb                   string   `json:"teaser_text"`
@@ -650,6 +680,8 @@ type ClsInstitution struct {
 
 // Settings represents a collection of settings
 type Settings struct {
+	itemMeta `json:"-"`
+
 	ContentID int               `json:"content_id"`
 	Settings  map[string]string `json:"settings"`
 }
@@ -676,6 +708,8 @@ func (c *Settings) GetPublicationDate() int64 {
 
 // Teaser represents ... something
 type Teaser struct {
+	itemMeta `json:"-"`
+
 	Type                    ItemType `json:"type"`
 	ContentID               int      `json:"content_id"`
 	Title                   string   `json:"title"`
@@ -713,6 +747,8 @@ func (t *Teaser) GetPublicationDate() int64 {
 
 // DownloadFile represents a file download object
 type DownloadFile struct {
+	itemMeta `json:"-"`
+
 	Type            ItemType `json:"type"`
 	ContentID       int      `json:"content_id"`
 	PublicationDate int64    `json:"publication_date"`
@@ -741,3 +777,39 @@ func (d *DownloadFile) GetTeaserText() string {
 func (d *DownloadFile) GetPublicationDate() int64 {
 	return d.PublicationDate
 }
+
+// GenericItem holds an item goib cannot decode, because its type is unknown or
+// UNSUPPORTED. It is only returned by APIs constructed with WithGenericItems.
+type GenericItem struct {
+	itemMeta `json:"-"`
+
+	Type            ItemType        `json:"type"`
+	ContentID       int             `json:"content_id"`
+	TeaserTitle     string          `json:"teaser_title"`
+	TeaserText      string          `json:"teaser_text"`
+	TeaserImage     string          `json:"teaser_image"`
+	PublicationDate int64           `json:"publication_date"`
+	Raw             json.RawMessage `json:"raw"`
+	// Reason is the error the item could not be decoded with, e.g. ErrUnknownType
+	Reason error `json:"-"`
+}
+
+func (g *GenericItem) GetType() ItemType {
+	return g.Type
+}
+
+func (g *GenericItem) GetContentID() int {
+	return g.ContentID
+}
+
+func (g *GenericItem) GetTeaserTitle() string {
+	return g.TeaserTitle
+}
+
+func (g *GenericItem) GetTeaserText() string {
+	return g.TeaserText
+}
+
+func (g *GenericItem) GetPublicationDate() int64 {
+	return g.PublicationDate
+}
@@ -1,8 +1,9 @@
 package goib
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"time"
@@ -21,30 +22,215 @@ var netClient = &http.Client{
 	Transport: netTransport,
 }
 
-// doGet is a method on the api object, but it's worth separating out here for clarity
-func (api *api) doGet(url string) (result []byte, err error) {
-	req, err := http.NewRequest("GET", url, nil)
+// response is the outcome of a successful request to IB
+type response struct {
+	body         []byte
+	etag         string
+	lastModified string
+	notModified  bool
+
+	// stale and age are set when the body was served from an expired cache entry
+	stale bool
+	age   time.Duration
+	// cached is set when the body is held by the cache
+	cached bool
+
+	// items hold the result of decoding a streamed array body, which is not kept
+	items    []Item
+	streamed bool
+	size     int64
+}
+
+// doGet is a method on the api object, but it's worth separating out here for clarity.
+// Responses are served from the cache when one is configured, and concurrent identical
+// requests are coalesced when enabled.
+func (api *api) doGet(ctx context.Context, req *request) (resp *response, err error) {
+	ctx, span := api.startSpan(ctx, "goib."+req.service, req)
+	defer func() { endSpan(span, resp, err) }()
+
+	if api.cache != nil {
+		return api.cachedGet(ctx, req)
+	}
+
+	return api.coalesce(ctx, req, func(ctx context.Context) (*response, error) {
+		return api.fetch(ctx, req, nil)
+	})
+}
+
+// fetch requests the given URI from IB, retrying failed attempts according to the
+// API's retry policy. If cached is set, the request is made conditional on it.
+func (api *api) fetch(ctx context.Context, req *request, cached *CacheEntry) (resp *response, err error) {
+	policy := &api.retryPolicy
+	maxAttempts := policy.maxAttempts()
+
+	attempt := 1
+	for {
+		resp, err = api.fetchOnce(ctx, req, cached)
+		if policy.OnAttempt != nil {
+			policy.OnAttempt(ctx, attempt, err)
+		}
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= maxAttempts || !policy.shouldRetry(ctx, err) {
+			break
+		}
+
+		delay := policy.delay(attempt, err)
+		api.logger.Debug("request to IB failed, retrying", req.logFields("attempt", attempt, "delay", delay, "error", err)...)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, delay)
+		}
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			err = sleepErr
+			break
+		}
+		attempt++
+	}
+
+	if attempt > 1 {
+		return nil, &RetryError{Attempts: attempt, Err: err}
+	}
+	return nil, err
+}
+
+func (api *api) fetchOnce(ctx context.Context, req *request, cached *CacheEntry) (resp *response, err error) {
+	if err = api.limiters.wait(ctx, req.channel); err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", req.uri, nil)
 	if err != nil {
-		log.Error("this shouldn't happen: %v", err)
+		api.logger.Error("this shouldn't happen", req.logFields("error", err)...)
+		return nil, err
+	}
+	httpReq.Header.Set("Accept-Encoding", acceptEncoding)
+	if api.userAgent != "" {
+		httpReq.Header.Set("User-Agent", api.userAgent)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			httpReq.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			httpReq.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	if api.hosts == nil {
+		return api.sendWithBreaker(ctx, req, httpReq, cached != nil)
+	}
+
+	// fail over to the next healthy host as long as the previous one is in trouble
+	for _, host := range api.hosts.candidates() {
+		hostReq := httpReq.Clone(ctx)
+		hostReq.URL.Host = host
+		hostReq.Host = host
+
+		resp, err = api.sendWithBreaker(ctx, req, hostReq, cached != nil)
+		if ctx.Err() != nil {
+			return resp, err
+		}
+		api.hosts.record(host, err)
+		if !isBackendFailure(err) {
+			return resp, err
+		}
+		api.logger.Debug("request to IB host failed", req.logFields("host", host, "error", err)...)
+	}
+
+	return resp, err
+}
+
+func (api *api) sendWithBreaker(ctx context.Context, req *request, httpReq *http.Request, conditional bool) (resp *response, err error) {
+	cb := api.breakers.forHost(httpReq.URL.Host)
+	if err = cb.allow(); err != nil {
+		api.logger.Debug("not sending request", req.logFields("host", httpReq.URL.Host, "error", err)...)
 		return nil, err
 	}
-	resp, err := api.client.Do(req)
+	spanCtx, span := api.startSpan(ctx, "goib.http", req)
+	if api.tracer != nil {
+		span.SetAttribute(AttrHost, httpReq.URL.Host)
+		httpReq = httpReq.WithContext(spanCtx)
+	}
+
+	start := time.Now()
+	resp, err = api.send(req, httpReq, conditional)
+	api.observeRequest(req, time.Since(start), resp, err)
+	endSpan(span, resp, err)
+	cb.done(ctx, err)
+
+	return resp, err
+}
+
+func (api *api) send(req *request, httpReq *http.Request, conditional bool) (*response, error) {
+	url := httpReq.URL.String()
+
+	httpResp, err := api.client.Do(httpReq)
 	if err != nil {
-		log.Debug("got error response for URL %s: %v", url, err)
+		api.logger.Debug("got error response", req.logFields("url", url, "error", err)...)
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer httpResp.Body.Close()
 
-	statusCode, status := resp.StatusCode, resp.Status
-	if statusCode != 200 {
-		return nil, fmt.Errorf("IB returned an error: %s: %s", status, url)
+	if conditional && httpResp.StatusCode == http.StatusNotModified {
+		api.logger.Trace("not modified", req.logFields("url", url)...)
+		return &response{notModified: true}, nil
+	}
+
+	if httpResp.StatusCode != 200 {
+		snippet := readSnippet(httpResp, maxErrorBodySnippet)
+		return nil, &HTTPError{
+			StatusCode: httpResp.StatusCode,
+			Status:     httpResp.Status,
+			URL:        url,
+			Body:       string(snippet),
+			retryAfter: httpResp.Header.Get("Retry-After"),
+		}
+	}
+
+	if req.stream != nil {
+		return api.sendStreamed(req, httpResp)
+	}
+
+	result, err := readBody(httpResp, api.maxBodySize)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w: %s", err, url)
+	}
+
+	api.logger.Trace("success", req.logFields("url", url)...)
+	return &response{
+		body:         result,
+		etag:         httpResp.Header.Get("ETag"),
+		lastModified: httpResp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// sendStreamed decodes the body of a successful response while it is being read
+func (api *api) sendStreamed(req *request, httpResp *http.Response) (*response, error) {
+	url := httpResp.Request.URL.String()
+
+	body, err := openBody(httpResp, api.maxBodySize)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w: %s", err, url)
 	}
+	defer body.Close()
 
-	result, err = ioutil.ReadAll(resp.Body)
+	// drops recorded while decoding an earlier, failed attempt don't apply to this one
+	req.report, req.failure = nil, nil
+	counter := &countingReader{r: body}
+	resp, err := req.stream(counter)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v: %s", err, url)
+		var de *DecodeError
+		if errors.As(err, &de) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error reading response body: %w: %s", err, url)
 	}
 
-	log.Trace("%s : SUCCESS", url)
-	return result, nil
+	api.logger.Trace("success", req.logFields("url", url)...)
+	resp.streamed = true
+	resp.size = counter.n
+	resp.etag = httpResp.Header.Get("ETag")
+	resp.lastModified = httpResp.Header.Get("Last-Modified")
+	return resp, nil
 }
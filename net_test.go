@@ -1,9 +1,12 @@
 package goib
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,9 +22,9 @@ func Test_doGet_canary(t *testing.T) {
 
 	a := NewAPI().(*api)
 
-	resp, err := a.doGet(testSvr.URL)
+	resp, err := a.doGet(context.Background(), &request{uri: testSvr.URL})
 	assert.Nil(t, err)
-	assert.Equal(t, []byte("I leik milk!!1\n"), resp)
+	assert.Equal(t, []byte("I leik milk!!1\n"), resp.body)
 }
 
 func Test_doGet_errorResponse(t *testing.T) {
@@ -33,26 +36,37 @@ func Test_doGet_errorResponse(t *testing.T) {
 
 	a := NewAPI().(*api)
 
-	_, err := a.doGet(testSvr.URL)
+	_, err := a.doGet(context.Background(), &request{uri: testSvr.URL})
 	assert.NotNil(t, err)
 	assert.Equal(t, "IB returned an error: 418 I'm a teapot: "+testSvr.URL, err.Error())
 }
 
 func Test_doGet_timeout(t *testing.T) {
-	oldTimeout := netClient.Timeout
-	netClient.Timeout = time.Millisecond
-
 	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(time.Millisecond * 2)
 		fmt.Fprintln(w, "I leik milk!!1")
 	}))
 	defer testSvr.Close()
 
-	a := NewAPI().(*api)
+	a := NewAPI(WithTimeout(time.Millisecond)).(*api)
 
-	_, err := a.doGet(testSvr.URL)
+	_, err := a.doGet(context.Background(), &request{uri: testSvr.URL})
 	assert.NotNil(t, err)
-	assert.Equal(t, fmt.Sprintf("Get %s: net/http: request canceled (Client.Timeout exceeded while awaiting headers)", testSvr.URL), err.Error())
+	assert.True(t, strings.Contains(err.Error(), "Client.Timeout exceeded while awaiting headers"), err.Error())
+	assert.Equal(t, 30*time.Second, netClient.Timeout, "shared client should not be modified")
+}
+
+func Test_doGet_contextCanceled(t *testing.T) {
+	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer testSvr.Close()
+
+	a := NewAPI().(*api)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
 
-	netClient.Timeout = oldTimeout
+	_, err := a.doGet(ctx, &request{uri: testSvr.URL})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), fmt.Sprint(err))
 }
@@ -0,0 +1,103 @@
+package goib
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultScheme = "http"
+const defaultVersion = "v2.0"
+
+// Option configures an API object constructed by NewAPI
+type Option func(*api)
+
+// WithHost sets the IB host requests are sent to. Defaults to ibsys-api.ib-prod.com.
+func WithHost(host string) Option {
+	return func(a *api) {
+		a.host = host
+	}
+}
+
+// WithHTTPS switches the request scheme from http to https
+func WithHTTPS() Option {
+	return func(a *api) {
+		a.scheme = "https"
+	}
+}
+
+// WithVersion sets the API version used in the base path, e.g. "v2.0"
+func WithVersion(version string) Option {
+	return func(a *api) {
+		a.version = strings.Trim(version, "/")
+	}
+}
+
+// WithHTTPClient replaces the shared package-level HTTP client with the supplied one.
+// WithTransport and WithTimeout are applied to a copy of this client, so it is never mutated.
+// A nil client leaves the default in place.
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *api) {
+		if client != nil {
+			a.client = client
+		}
+	}
+}
+
+// WithTransport sets the RoundTripper used to send requests to IB
+func WithTransport(transport http.RoundTripper) Option {
+	return func(a *api) {
+		a.transport = transport
+	}
+}
+
+// WithTimeout sets the overall timeout for a single HTTP request to IB
+func WithTimeout(timeout time.Duration) Option {
+	return func(a *api) {
+		a.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(a *api) {
+		a.userAgent = userAgent
+	}
+}
+
+// WithDefaultParams sets query parameters that are sent with every request. Params
+// passed to an individual call take precedence over these.
+func WithDefaultParams(params url.Values) Option {
+	return func(a *api) {
+		a.defaultParams = url.Values{}
+		for k, v := range params {
+			a.defaultParams[k] = append([]string(nil), v...)
+		}
+	}
+}
+
+// setupClient derives the HTTP client from the configured client, transport, timeout and
+// middlewares. The configured client is copied rather than modified so that shared clients
+// stay untouched.
+func (api *api) setupClient() {
+	if api.transport == nil && api.timeout == 0 && len(api.middleware) == 0 {
+		return
+	}
+
+	client := *api.client
+	if api.transport != nil {
+		client.Transport = api.transport
+	}
+	if api.timeout != 0 {
+		client.Timeout = api.timeout
+	}
+	if len(api.middleware) > 0 {
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		client.Transport = chainMiddleware(transport, api.middleware)
+	}
+	api.client = &client
+}
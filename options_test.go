@@ -0,0 +1,66 @@
+package goib
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIShouldApplyDefaults(t *testing.T) {
+	a := NewAPI().(*api)
+
+	assert.Equal(t, "http://ibsys-api.ib-prod.com/v2.0/delivery/wkrp/json/content", a.setupURI("wkrp", "content"))
+	assert.Equal(t, netClient, a.client)
+}
+
+func TestNewAPIShouldApplyURIOptions(t *testing.T) {
+	a := NewAPI(WithHTTPS(), WithHost("ib.example.com"), WithVersion("/v3.1/")).(*api)
+
+	assert.Equal(t, "https://ib.example.com/v3.1/delivery/wkrp/json/entry", a.setupURI("wkrp", "entry"))
+}
+
+func TestNewAPIShouldCopyClientForTransportAndTimeout(t *testing.T) {
+	client := &http.Client{Timeout: time.Minute}
+	transport := &http.Transport{}
+
+	a := NewAPI(WithTimeout(time.Second), WithTransport(transport), WithHTTPClient(client)).(*api)
+
+	assert.True(t, a.client != client, "supplied client should have been copied")
+	assert.Equal(t, time.Second, a.client.Timeout)
+	assert.Equal(t, transport, a.client.Transport)
+	assert.Equal(t, time.Minute, client.Timeout, "supplied client should not be modified")
+	assert.Nil(t, client.Transport, "supplied client should not be modified")
+}
+
+func TestNewAPIShouldIgnoreNilHTTPClient(t *testing.T) {
+	a := NewAPI(WithHTTPClient(nil)).(*api)
+	assert.Equal(t, netClient, a.client)
+
+	a = NewAPI(WithHTTPClient(nil), WithTimeout(time.Second)).(*api)
+	assert.Equal(t, time.Second, a.client.Timeout)
+}
+
+func TestShouldSendUserAgentAndDefaultParams(t *testing.T) {
+	var gotUA string
+	var gotQuery url.Values
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		gotQuery = r.URL.Query()
+		fmt.Fprintln(w, imageJSON)
+	}, WithUserAgent("goib-test/1.0"), WithDefaultParams(url.Values{"apikey": {"s3cr3t"}, "limit": {"10"}}))
+	defer testSvr.Close()
+
+	_, err := a.Content("wkrp", 12345, url.Values{"limit": {"20"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "goib-test/1.0", gotUA)
+	assert.Equal(t, "s3cr3t", gotQuery.Get("apikey"))
+	assert.Equal(t, "20", gotQuery.Get("limit"), "call params should override defaults")
+
+	_, err = a.Closings("wkrp", ClosingsCount)
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", gotQuery.Get("apikey"))
+}
@@ -0,0 +1,127 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned instead of sending a request that would exceed the rate
+// limit of its channel, if the limiter is configured to fail fast
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimit configures a client-side token bucket per channel. Only requests actually
+// sent to IB count against the limit; cache hits do not.
+type RateLimit struct {
+	// Rate is the number of requests per second allowed per channel. A Rate of zero or less
+	// turns the limiter off.
+	Rate float64
+	// Burst is the number of requests per channel that may be sent at once
+	Burst int
+	// FailFast makes requests fail with ErrRateLimited instead of waiting for a token
+	FailFast bool
+	// OnWait, if set, is called for every request with the time it waited for the limiter
+	OnWait func(channel string, wait time.Duration)
+}
+
+// WithRateLimit limits the rate of requests sent to IB per channel. It does nothing
+// unless limit.Rate is positive.
+func WithRateLimit(limit RateLimit) Option {
+	return func(a *api) {
+		if limit.Rate <= 0 {
+			a.limiters = nil
+			return
+		}
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		a.limiters = &rateLimiters{
+			limit:   limit,
+			now:     time.Now,
+			logger:  packageLogger,
+			buckets: make(map[string]*tokenBucket),
+		}
+	}
+}
+
+type rateLimiters struct {
+	limit  RateLimit
+	now    func() time.Time
+	logger Logger
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// wait blocks until a request may be sent for the channel, or fails with ErrRateLimited
+// if the limiter fails fast
+func (rl *rateLimiters) wait(ctx context.Context, channel string) error {
+	if rl == nil {
+		return nil
+	}
+
+	delay, err := rl.reserve(channel)
+	if err != nil {
+		rl.logger.Debug("rate limit exceeded", "channel", channel)
+		return err
+	}
+	if rl.limit.OnWait != nil {
+		rl.limit.OnWait(channel, delay)
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	rl.logger.Trace("rate limiting request", "channel", channel, "delay", delay)
+	if err := sleepContext(ctx, delay); err != nil {
+		rl.release(channel)
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token from the channel's bucket and returns how long to wait before
+// it may be used
+func (rl *rateLimiters) reserve(channel string) (time.Duration, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	b, ok := rl.buckets[channel]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rl.limit.Burst), last: now}
+		rl.buckets[channel] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * rl.limit.Rate
+	if b.tokens > float64(rl.limit.Burst) {
+		b.tokens = float64(rl.limit.Burst)
+	}
+	b.last = now
+
+	if rl.limit.FailFast && b.tokens < 1 {
+		return 0, ErrRateLimited
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0, nil
+	}
+	return time.Duration(-b.tokens / rl.limit.Rate * float64(time.Second)), nil
+}
+
+// release returns a reserved token that was not used
+func (rl *rateLimiters) release(channel string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if b, ok := rl.buckets[channel]; ok {
+		b.tokens++
+	}
+}
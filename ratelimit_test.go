@@ -0,0 +1,107 @@
+package goib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRateLimitedServerAndAPI(limit RateLimit) (*httptest.Server, API) {
+	return setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, imageJSON)
+	}, WithRateLimit(limit))
+}
+
+func TestRateLimitShouldFailFastPerChannel(t *testing.T) {
+	svr, a := setupRateLimitedServerAndAPI(RateLimit{Rate: 0.001, Burst: 2, FailFast: true})
+	defer svr.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err := a.Content("wkrp", 12345, nil)
+		assert.Nil(t, err)
+	}
+	_, err := a.Content("wkrp", 12345, nil)
+	assert.Equal(t, ErrRateLimited, err)
+
+	_, err = a.Content("wbal", 12345, nil)
+	assert.Nil(t, err, "other channels should have their own bucket")
+}
+
+func TestRateLimitShouldBlockAndReportWaits(t *testing.T) {
+	var mu sync.Mutex
+	var waits []time.Duration
+	svr, a := setupRateLimitedServerAndAPI(RateLimit{
+		Rate:  50,
+		Burst: 1,
+		OnWait: func(channel string, wait time.Duration) {
+			mu.Lock()
+			waits = append(waits, wait)
+			mu.Unlock()
+		},
+	})
+	defer svr.Close()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := a.Content("wkrp", 12345, nil)
+		assert.Nil(t, err)
+	}
+
+	assert.True(t, time.Since(start) >= 30*time.Millisecond, "requests should have been spaced out")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, len(waits))
+	assert.Equal(t, time.Duration(0), waits[0])
+	assert.True(t, waits[1] > 0)
+}
+
+func TestRateLimitShouldHonorContextWhileWaiting(t *testing.T) {
+	svr, a := setupRateLimitedServerAndAPI(RateLimit{Rate: 0.001, Burst: 1})
+	defer svr.Close()
+
+	_, err := a.Content("wkrp", 12345, nil)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = a.ContentContext(ctx, "wkrp", 12345, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestTokenBucketShouldRefill(t *testing.T) {
+	now := time.Now()
+	rl := &rateLimiters{
+		limit:   RateLimit{Rate: 10, Burst: 1},
+		now:     func() time.Time { return now },
+		buckets: make(map[string]*tokenBucket),
+	}
+
+	d, _ := rl.reserve("wkrp")
+	assert.Equal(t, time.Duration(0), d)
+	d, _ = rl.reserve("wkrp")
+	assert.Equal(t, 100*time.Millisecond, d)
+
+	now = now.Add(200 * time.Millisecond)
+	d, _ = rl.reserve("wkrp")
+	assert.Equal(t, time.Duration(0), d)
+}
+
+func TestRateLimitWithoutRateShouldBeOff(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		svr, a := setupRateLimitedServerAndAPI(RateLimit{Rate: rate, Burst: 2, FailFast: true})
+
+		for i := 0; i < 4; i++ {
+			_, err := a.Content("wkrp", 12345, nil)
+			assert.Nil(t, err, "request %d", i)
+		}
+		assert.Nil(t, a.(*api).limiters)
+
+		svr.Close()
+	}
+}
@@ -0,0 +1,243 @@
+package goib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WithGenericItems keeps items of unknown and UNSUPPORTED types as *GenericItem, holding
+// their raw JSON, instead of dropping them.
+func WithGenericItems() Option {
+	return func(a *api) {
+		a.genericItems = true
+	}
+}
+
+// WithRawJSON attaches the raw JSON of every decoded item to the item, where Raw and
+// LookupRaw can get at fields goib doesn't model. The raw JSON is captured while decoding.
+func WithRawJSON() Option {
+	return func(a *api) {
+		a.rawJSON = true
+	}
+}
+
+// keepsRaw reports whether the raw JSON of decoded objects is needed
+func (api *api) keepsRaw() bool {
+	return api.genericItems || api.rawJSON
+}
+
+// rawJSON returns data as the raw JSON of an object. Its capacity is capped, so that
+// appending to it can't overwrite the JSON of the objects following it.
+func rawJSON(data []byte) json.RawMessage {
+	return data[:len(data):len(data)]
+}
+
+// ownBody returns the body of resp for decoding it while keeping raw JSON, which refers to
+// the body. A body held by the cache or shared by coalesced calls is copied first, so that
+// the items of one call can't change what later calls decode.
+func (api *api) ownBody(resp *response) []byte {
+	if resp.cached || api.flights != nil {
+		return append([]byte(nil), resp.body...)
+	}
+	return resp.body
+}
+
+// Raw returns the JSON item was decoded from, or nil if the API that decoded it wasn't
+// constructed with WithRawJSON. The JSON of an item includes its sub-objects and is part
+// of the JSON of the item holding it, so it must not be modified in place.
+func Raw(item Item) json.RawMessage {
+	if m := getMeta(item); m != nil && m.raw != nil {
+		return m.raw
+	}
+	if g, ok := item.(*GenericItem); ok {
+		return g.Raw
+	}
+	return nil
+}
+
+// LookupRaw returns the JSON found at path within the raw JSON of item. Path elements
+// are separated by dots and name object members, as in "teaser_image.url"; "[n]" selects
+// element n of an array, as in "categories[0].name". For convenience, a dotted element
+// made up of digits selects an array element as well, so "categories.0.name" works too,
+// while it still names a member such as "2024" of an object. A backslash escapes the
+// character that follows it, so that member names containing '.', '[', ']' or '\' can be
+// looked up, e.g. "labels.en\.gb".
+func LookupRaw(item Item, path string) (json.RawMessage, bool) {
+	return LookupJSON(Raw(item), path)
+}
+
+// DecodeRaw unmarshals the JSON found at path within the raw JSON of item into v
+func DecodeRaw(item Item, path string, v interface{}) error {
+	value, ok := LookupRaw(item, path)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRawPathNotFound, path)
+	}
+	return json.Unmarshal(value, v)
+}
+
+// ErrRawPathNotFound is returned by DecodeRaw if nothing is found at the given path
+var ErrRawPathNotFound = errors.New("path not found in raw JSON")
+
+// LookupJSON returns the JSON found at path within data, using the path syntax of
+// LookupRaw. It reports false if data isn't valid JSON or path is malformed.
+func LookupJSON(data json.RawMessage, path string) (json.RawMessage, bool) {
+	segments, ok := parsePath(path)
+	if !ok || !json.Valid(data) {
+		return nil, false
+	}
+
+	value := bytes.TrimSpace(data)
+	for _, seg := range segments {
+		if value, ok = seg.lookup(value); !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// pathSegment is an element of a path passed to LookupJSON
+type pathSegment struct {
+	key string
+	// index is the array element selected by the segment, or -1 if it selects none
+	index int
+	// keyed is set for dotted segments, which name object members. Segments written as
+	// "[n]" only select array elements.
+	keyed bool
+}
+
+// parsePath splits path into segments, reporting false if it is malformed
+func parsePath(path string) (segments []pathSegment, ok bool) {
+	var key strings.Builder
+	pending := false
+	flush := func() {
+		if pending {
+			segments = append(segments, keySegment(key.String()))
+		}
+		key.Reset()
+		pending = false
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '\\':
+			if i++; i == len(path) {
+				return nil, false
+			}
+			key.WriteByte(path[i])
+			pending = true
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, false
+			}
+			index := parseIndex(path[i+1 : i+end])
+			if index < 0 {
+				return nil, false
+			}
+			segments = append(segments, pathSegment{index: index})
+			i += end
+			if i+1 < len(path) && path[i+1] != '.' && path[i+1] != '[' {
+				return nil, false
+			}
+		case ']':
+			return nil, false
+		default:
+			key.WriteByte(c)
+			pending = true
+		}
+	}
+	flush()
+	return segments, true
+}
+
+func keySegment(key string) pathSegment {
+	return pathSegment{key: key, index: parseIndex(key), keyed: true}
+}
+
+// parseIndex returns the array index written as s, or -1 if s isn't made up of digits
+func parseIndex(s string) int {
+	if s == "" {
+		return -1
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return -1
+		}
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return -1
+	}
+	return index
+}
+
+// lookup returns the value selected by seg within the valid JSON value data, scanning
+// data without decoding it
+func (seg pathSegment) lookup(data []byte) (value []byte, found bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+
+	switch data[0] {
+	case '{':
+		if !seg.keyed {
+			return nil, false
+		}
+		// like encoding/json, the last of several members with the same name wins
+		for elem, rest := nextElement(data[1:]); elem != nil; elem, rest = nextElement(rest) {
+			end := skipString(elem, 0)
+			if memberNamed(elem[:end+1], seg.key) {
+				value = bytes.TrimSpace(elem[bytes.IndexByte(elem[end:], ':')+end+1:])
+				found = true
+			}
+		}
+		return value, found
+	case '[':
+		if seg.index < 0 {
+			return nil, false
+		}
+		i := 0
+		for elem, rest := nextElement(data[1:]); elem != nil; elem, rest = nextElement(rest) {
+			if i == seg.index {
+				return elem, true
+			}
+			i++
+		}
+	}
+	return nil, false
+}
+
+// memberNamed reports whether the quoted member name of a JSON object equals key
+func memberNamed(quoted []byte, key string) bool {
+	if bytes.IndexByte(quoted, '\\') < 0 {
+		return string(quoted[1:len(quoted)-1]) == key
+	}
+	var name string
+	return json.Unmarshal(quoted, &name) == nil && name == key
+}
+
+// genericItem wraps a Receiver that cannot be decoded for the reason given by err
+func genericItem(r Receiver, err error) *GenericItem {
+	return &GenericItem{
+		Type:            r.Type,
+		ContentID:       r.ContentID,
+		TeaserTitle:     r.TeaserTitle,
+		TeaserText:      r.TeaserText,
+		TeaserImage:     r.TeaserImage,
+		PublicationDate: r.PublicationDate,
+		Raw:             r.raw,
+		Reason:          err,
+	}
+}
+
+// isUndecodableType reports whether err means that no decoder could handle an item's type
+func isUndecodableType(err error) bool {
+	return errors.Is(err, ErrUnknownType) || err == ErrUnsupportedType
+}
@@ -0,0 +1,176 @@
+package goib
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var collectionWithUndecodableItemsJSON = `{"type":"COLLECTION","content_id":1,"items":[
+	{"type":"POLL","content_id":2,"teaser_title":"Rain?","answers":["yes","no"]},
+	{"type":"UNSUPPORTED","content_id":3},
+	` + imageJSON + `
+]}`
+
+func TestShouldKeepUndecodableItemsAsGenericItems(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		svr, a, _ := setupDecodingServerAndAPI(collectionWithUndecodableItemsJSON, streaming, WithGenericItems())
+
+		item, err := a.Content("someKrazyChannel", 1, nil)
+		assert.Nil(t, err)
+		c := item.(*Collection)
+		assert.Equal(t, 3, len(c.Items))
+
+		poll := c.Items[0].(*GenericItem)
+		assert.Equal(t, ItemType("POLL"), poll.GetType())
+		assert.Equal(t, 2, poll.GetContentID())
+		assert.Equal(t, "Rain?", poll.GetTeaserTitle())
+		assert.True(t, errors.Is(poll.Reason, ErrUnknownType))
+		var raw map[string]interface{}
+		assert.Nil(t, json.Unmarshal(poll.Raw, &raw))
+		assert.Equal(t, []interface{}{"yes", "no"}, raw["answers"])
+
+		unsupported := c.Items[1].(*GenericItem)
+		assert.Equal(t, ItemType(UnsupportedType), unsupported.GetType())
+		assert.Equal(t, ErrUnsupportedType, unsupported.Reason)
+
+		assert.Equal(t, ImageType, c.Items[2].GetType())
+
+		svr.Close()
+	}
+}
+
+func TestShouldDropUndecodableItemsByDefault(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI(collectionWithUndecodableItemsJSON, true)
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(item.(*Collection).Items))
+}
+
+func TestShouldReturnGenericItemForUnknownTopLevelType(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI(`{"type":"POLL","content_id":2}`, false, WithGenericItems())
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 2, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"type":"POLL","content_id":2}`, string(item.(*GenericItem).Raw))
+
+	items, err := a.ContentItems("someKrazyChannel", 2, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, items)
+}
+
+func TestShouldKeepGenericItemsInArrays(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		svr, a, _ := setupDecodingServerAndAPI(`[{"type":"POLL","content_id":2},`+imageJSON+`]`, streaming, WithGenericItems())
+
+		items, err := a.ContentItems("someKrazyChannel", 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(items))
+		assert.Equal(t, `{"type":"POLL","content_id":2}`, string(items[0].(*GenericItem).Raw))
+
+		svr.Close()
+	}
+}
+
+var collectionWithExtraFieldsJSON = `{"type":"COLLECTION","content_id":1,"valid_from":1500000000,
+	"categories":[{"name":"news"},{"name":"weather"}],"items":[
+	{"type":"IMAGE","content_id":2,"copyright":"ACME","focal_point":{"x":0.5,"y":0.25}}
+]}`
+
+func TestShouldAttachRawJSONToDecodedItems(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		svr, a, _ := setupDecodingServerAndAPI(collectionWithExtraFieldsJSON, streaming, WithRawJSON())
+
+		item, err := a.Content("someKrazyChannel", 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, collectionWithExtraFieldsJSON, string(Raw(item)))
+
+		value, ok := LookupRaw(item, "categories[1].name")
+		assert.True(t, ok)
+		assert.Equal(t, `"weather"`, string(value))
+		value, ok = LookupRaw(item, "items.0.focal_point.y")
+		assert.True(t, ok)
+		assert.Equal(t, `0.25`, string(value))
+
+		var validFrom int64
+		assert.Nil(t, DecodeRaw(item, "valid_from", &validFrom))
+		assert.Equal(t, int64(1500000000), validFrom)
+
+		image := item.(*Collection).Items[0]
+		var copyright string
+		assert.Nil(t, DecodeRaw(image, "copyright", &copyright))
+		assert.Equal(t, "ACME", copyright)
+
+		svr.Close()
+	}
+}
+
+func TestRawJSONShouldNotAffectOtherItemsOrCalls(t *testing.T) {
+	collection := `{"type":"COLLECTION","content_id":1,"items":[{"type":"IMAGE","content_id":2},{"type":"IMAGE","content_id":3}]}`
+	svr, a, calls := setupCachingServerAndAPI(collection, CacheConfig{TTL: time.Minute}, nil, WithRawJSON())
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	items := item.(*Collection).Items
+	_ = append(Raw(items[0]), `,"garbage"`...)
+	assert.Equal(t, `{"type":"IMAGE","content_id":3}`, string(Raw(items[1])))
+
+	raw := Raw(item)
+	for i := range raw {
+		raw[i] = ' '
+	}
+	item, err = a.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(item.(*Collection).Items))
+	assert.Equal(t, int32(1), *calls)
+}
+
+func TestShouldNotAttachRawJSONByDefault(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI(collectionWithExtraFieldsJSON, true)
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, Raw(item))
+	_, ok := LookupRaw(item, "valid_from")
+	assert.False(t, ok)
+	assert.True(t, errors.Is(DecodeRaw(item, "valid_from", new(int64)), ErrRawPathNotFound))
+}
+
+func TestLookupJSON(t *testing.T) {
+	data := json.RawMessage(`{"a":{"b":[1,{"c":"d"}]},"e":null,"f.g":{"[h]":true},"2024":"y","i\u002ej":1,
+		"k": [ "l" , "m\"]" ] }`)
+
+	for path, expected := range map[string]string{
+		"":           string(data),
+		"a.b.0":      `1`,
+		"a.b[1]":     `{"c":"d"}`,
+		"a.b.1.c":    `"d"`,
+		"a.b[1].c":   `"d"`,
+		"e":          `null`,
+		`f\.g.\[h\]`: `true`,
+		"2024":       `"y"`,
+		`i\.j`:       `1`,
+		"k[1]":       `"m\"]"`,
+	} {
+		value, ok := LookupJSON(data, path)
+		assert.True(t, ok, path)
+		assert.Equal(t, expected, string(value), path)
+	}
+
+	for _, path := range []string{"x", "a.b.2", "a.b.-1", "a.b.+1", "a.b.c", "a.b.0.c", "e.f", "f.g",
+		"[0]", "a[0]", "a.b[x]", "a.b[1", "a.b]", "a.b[1]c", `a\`} {
+		_, ok := LookupJSON(data, path)
+		assert.False(t, ok, path)
+	}
+
+	_, ok := LookupJSON(json.RawMessage(`{"a":`), "a")
+	assert.False(t, ok)
+}
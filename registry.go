@@ -0,0 +1,101 @@
+package goib
+
+import "sync"
+
+// Decoder is handed to DecoderFuncs so that they can unmarshal the sub-objects of the
+// Receiver they decode
+type Decoder interface {
+	// UnmarshalReceiver turns r into an Item using the decoder registered for its type
+	UnmarshalReceiver(r Receiver) (Item, error)
+	// UnmarshalChildren unmarshals the sub-objects held in the given field of r, e.g.
+	// UnmarshalChildren(&r, "items", r.Items). field is the JSON name of the field:
+	// "media", "related_media" or "items". Sub-objects that cannot be unmarshalled are
+	// logged, recorded in the decode report and left out. When the response was
+	// streamed, the sub-objects have already been unmarshalled and children is empty,
+	// so decoders must always go through UnmarshalChildren rather than range over it.
+	UnmarshalChildren(r *Receiver, field string, children []Receiver) []Item
+	// Logger returns the logger of the API doing the decoding, as set with WithLogger
+	Logger() Logger
+}
+
+// DecoderFunc turns a Receiver of a registered type into an Item
+type DecoderFunc func(d Decoder, r Receiver) (Item, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[ItemType]DecoderFunc)
+)
+
+// RegisterType registers the decoder for an IB item type for all APIs, replacing any
+// decoder registered before, including the built-in ones. Registering a nil decoder
+// removes the type, so that it is reported as unknown. RegisterType is meant to be
+// called during initialization, but is safe to call at any time.
+func RegisterType(itemType ItemType, decode DecoderFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if decode == nil {
+		delete(registry, itemType)
+	} else {
+		registry[itemType] = decode
+	}
+}
+
+// WithType registers the decoder for an IB item type for a single API, taking precedence
+// over the decoders registered with RegisterType
+func WithType(itemType ItemType, decode DecoderFunc) Option {
+	return func(a *api) {
+		if a.types == nil {
+			a.types = make(map[ItemType]DecoderFunc)
+		}
+		a.types[itemType] = decode
+	}
+}
+
+// decoderFor returns the decoder for itemType, or nil if the type is unknown
+func (api *api) decoderFor(itemType ItemType) DecoderFunc {
+	if decode, ok := api.types[itemType]; ok {
+		return decode
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[itemType]
+}
+
+// decoder is the Decoder handed to DecoderFuncs. It keeps the helpers for decoders off
+// the API itself.
+type decoder struct {
+	api *api
+}
+
+func (d decoder) UnmarshalReceiver(r Receiver) (Item, error) {
+	return d.api.UnmarshalReceiver(r)
+}
+
+func (d decoder) UnmarshalChildren(r *Receiver, field string, children []Receiver) []Item {
+	return d.api.unmarshalChildren(r, field, children)
+}
+
+func (d decoder) Logger() Logger {
+	return d.api.logger
+}
+
+func init() {
+	RegisterType(ArticleType, func(d Decoder, r Receiver) (Item, error) { return unmarshalArticle(d, r), nil })
+	RegisterType(VideoType, func(d Decoder, r Receiver) (Item, error) { return unmarshalVideo(d, r), nil })
+	RegisterType(CollectionType, func(d Decoder, r Receiver) (Item, error) { return unmarshalCollection(d, r), nil })
+	RegisterType(SearchType, func(d Decoder, r Receiver) (Item, error) { return unmarshalSearch(d, r), nil })
+	RegisterType(ImageType, func(d Decoder, r Receiver) (Item, error) { return unmarshalImage(r), nil })
+	RegisterType(GalleryType, func(d Decoder, r Receiver) (Item, error) { return unmarshalGallery(d, r), nil })
+	RegisterType(MapType, func(d Decoder, r Receiver) (Item, error) { return unmarshalMap(r), nil })
+	RegisterType(ExternalContentType, func(d Decoder, r Receiver) (Item, error) { return unmarshalExternalContent(r), nil })
+	RegisterType(ExternalLinkType, func(d Decoder, r Receiver) (Item, error) { return unmarshalExternalLink(d, r), nil })
+	RegisterType(HTMLType, func(d Decoder, r Receiver) (Item, error) { return unmarshalHTMLContent(r), nil })
+	RegisterType(PersonType, func(d Decoder, r Receiver) (Item, error) { return unmarshalPerson(r), nil })
+	RegisterType(LivevideoType, func(d Decoder, r Receiver) (Item, error) { return unmarshalLivevideo(d, r), nil })
+	RegisterType(SettingsType, func(d Decoder, r Receiver) (Item, error) { return unmarshalSettings(r), nil })
+	RegisterType(AudioType, func(d Decoder, r Receiver) (Item, error) { return unmarshalAudio(d, r), nil })
+	RegisterType(TeaserType, func(d Decoder, r Receiver) (Item, error) { return unmarshalTeaser(d, r) })
+	RegisterType(DownloadFileType, func(d Decoder, r Receiver) (Item, error) { return unmarshalDownloadFile(d, r), nil })
+	RegisterType(UnsupportedType, func(d Decoder, r Receiver) (Item, error) { return nil, ErrUnsupportedType })
+}
@@ -0,0 +1,68 @@
+package goib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type poll struct {
+	Type      ItemType
+	ContentID int
+	Question  string
+	Answers   []Item
+}
+
+func (p *poll) GetType() ItemType         { return p.Type }
+func (p *poll) GetContentID() int         { return p.ContentID }
+func (p *poll) GetTeaserTitle() string    { return p.Question }
+func (p *poll) GetTeaserText() string     { return "" }
+func (p *poll) GetPublicationDate() int64 { return 0 }
+
+const pollType ItemType = "POLL"
+
+func decodePoll(d Decoder, r Receiver) (Item, error) {
+	return &poll{Type: r.Type, ContentID: r.ContentID, Question: r.Title, Answers: d.UnmarshalChildren(&r, "items", r.Items)}, nil
+}
+
+func TestShouldDecodeRegisteredTypes(t *testing.T) {
+	RegisterType(pollType, decodePoll)
+	defer RegisterType(pollType, nil)
+
+	for _, streaming := range []bool{false, true} {
+		svr, a, _ := setupDecodingServerAndAPI(`{"type":"COLLECTION","content_id":1,"items":[{"type":"POLL","content_id":2,"title":"Rain?","items":[`+imageJSON+`]}]}`, streaming)
+
+		item, err := a.Content("someKrazyChannel", 1, nil)
+		assert.Nil(t, err)
+		c := item.(*Collection)
+		assert.Equal(t, 1, len(c.Items))
+		p := c.Items[0].(*poll)
+		assert.Equal(t, "Rain?", p.Question)
+		assert.Equal(t, 1, len(p.Answers))
+
+		svr.Close()
+	}
+}
+
+func TestUnregisteredTypesShouldBeUnknown(t *testing.T) {
+	RegisterType(pollType, decodePoll)
+	RegisterType(pollType, nil)
+
+	_, err := NewAPI().UnmarshalReceiver(Receiver{Type: pollType, ContentID: 2})
+	assert.True(t, errors.Is(err, ErrUnknownType))
+}
+
+func TestWithTypeShouldOverrideBuiltInDecoders(t *testing.T) {
+	custom := NewAPI(WithType(ImageType, func(d Decoder, r Receiver) (Item, error) {
+		return &Image{Type: r.Type, ContentID: r.ContentID, Title: "overridden"}, nil
+	}))
+
+	item, err := custom.UnmarshalReceiver(Receiver{Type: ImageType, ContentID: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, "overridden", item.(*Image).Title)
+
+	item, err = NewAPI().UnmarshalReceiver(Receiver{Type: ImageType, ContentID: 3, Title: "built in"})
+	assert.Nil(t, err)
+	assert.Equal(t, "built in", item.(*Image).Title)
+}
@@ -0,0 +1,159 @@
+package goib
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DecodeReport lists the sub-objects that were left out of a decoded item because they
+// couldn't be unmarshalled. Unless decoding strictly, goib keeps decoding when a
+// sub-object fails, so a report is the only way to tell that a tree is incomplete.
+type DecodeReport struct {
+	Dropped []DroppedObject
+}
+
+// DroppedObject describes a sub-object left out of a decoded item
+type DroppedObject struct {
+	// ParentID and ParentType identify the object the sub-object belonged to. They are
+	// zero for the elements of an array response such as ContentItems.
+	ParentID   int
+	ParentType ItemType
+	// Field is the field of the parent holding the sub-object: "media", "related_media",
+	// "items" or "target", or "" for the elements of an array response
+	Field string
+	// Path locates the sub-object within the response, e.g. "items.3". A teaser dropped
+	// because of its target is located at the target, e.g. "items.3.target".
+	Path      string
+	ContentID int
+	Type      ItemType
+	Reason    error
+}
+
+func (d DroppedObject) String() string {
+	return fmt.Sprintf("%s (%s %d): %v", d.Path, d.Type, d.ContentID, d.Reason)
+}
+
+// Complete reports whether nothing was dropped
+func (r *DecodeReport) Complete() bool {
+	return r == nil || len(r.Dropped) == 0
+}
+
+// Report returns the decode report of an item returned by the API, or nil if nothing
+// was dropped while decoding it. All items returned by one array call share a report.
+func Report(item Item) *DecodeReport {
+	if m := getMeta(item); m != nil {
+		return m.report
+	}
+	return nil
+}
+
+func setReport(item Item, report *DecodeReport) {
+	if m := getMeta(item); m != nil && report != nil {
+		m.report = report
+	}
+}
+
+// decodedChild is a sub-object together with the outcome of unmarshalling it
+type decodedChild struct {
+	item      Item
+	err       error
+	contentID int
+	itemType  ItemType
+	path      string
+}
+
+func newDecodedChild(r *Receiver, item Item, err error) decodedChild {
+	child := decodedChild{item: item, err: err, contentID: r.ContentID, itemType: r.Type}
+	if te, ok := err.(*targetError); ok {
+		// the teaser is dropped for its target, which is what the path should point at
+		child.err = te.err
+		child.path = te.path
+	} else if err != nil {
+		child.path = r.at.String()
+	}
+	return child
+}
+
+// targetError is returned for a teaser whose target couldn't be unmarshalled. The teaser
+// is dropped in its place, so that one bad target is reported once.
+type targetError struct {
+	err  error
+	path string
+}
+
+func (e *targetError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error of the target
+func (e *targetError) Unwrap() error {
+	return e.err
+}
+
+// location locates an object within a response. Its path is only built when needed, as
+// most objects decode fine.
+type location struct {
+	// parent is the path of the object holding this one
+	parent string
+	// field is the field of the parent holding this object, "" for array responses
+	field string
+	// index is the position of this object within field, if indexed
+	index   int
+	indexed bool
+}
+
+// String returns the dotted path of the location, "" for the top-level object
+func (l location) String() string {
+	path := joinPath(l.parent, l.field)
+	if !l.indexed {
+		return path
+	}
+	return joinPath(path, strconv.Itoa(l.index))
+}
+
+// drop records a sub-object of parent that was left out, parent being nil for the
+// elements of an array response. Once a strict request has failed, nothing else is
+// recorded.
+func (req *request) drop(parent *Receiver, field string, child decodedChild) {
+	if req == nil || req.failure != nil {
+		return
+	}
+	if req.report == nil {
+		req.report = &DecodeReport{}
+	}
+
+	d := DroppedObject{
+		Field:     field,
+		Path:      child.path,
+		ContentID: child.contentID,
+		Type:      child.itemType,
+		Reason:    child.err,
+	}
+	if parent != nil {
+		d.ParentID = parent.ContentID
+		d.ParentType = parent.Type
+	}
+	req.report.Dropped = append(req.report.Dropped, d)
+
+	if req.strict {
+		req.failure = &DecodeError{ContentID: d.failedID(req), Path: child.path, Err: child.err}
+	}
+}
+
+// failed reports whether a strict request has already failed, in which case there is no
+// point in decoding the rest of its response
+func (req *request) failed() bool {
+	return req != nil && req.failure != nil
+}
+
+// failedID returns the ID of the object closest to the dropped one, falling back to the
+// requested content ID for objects without IDs
+func (d DroppedObject) failedID(req *request) int {
+	switch {
+	case d.ContentID != 0:
+		return d.ContentID
+	case d.ParentID != 0:
+		return d.ParentID
+	}
+	return req.contentID
+}
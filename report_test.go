@@ -0,0 +1,109 @@
+package goib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var collectionWithBrokenItemsJSON = `{"type":"COLLECTION","content_id":1,"items":[
+	{"type":"POLL","content_id":2},
+	{"type":"TEASER","content_id":3},
+	{"type":"TEASER","content_id":4,"target":{"type":"POLL","content_id":5}},
+	{"type":"ARTICLE","content_id":6,"media":[{"type":"UNSUPPORTED","content_id":7},` + imageJSON + `]},
+	` + imageJSON + `
+]}`
+
+func droppedByPath(report *DecodeReport) map[string]DroppedObject {
+	result := make(map[string]DroppedObject)
+	for _, d := range report.Dropped {
+		result[d.Path] = d
+	}
+	return result
+}
+
+func assertBrokenItemsReport(t *testing.T, item Item) {
+	c := item.(*Collection)
+	assert.Equal(t, 2, len(c.Items))
+	assert.Equal(t, 1, len(c.Items[0].(*Article).Media))
+
+	report := Report(item)
+	assert.False(t, report.Complete())
+	assert.Equal(t, 4, len(report.Dropped))
+	dropped := droppedByPath(report)
+
+	poll := dropped["items.0"]
+	assert.Equal(t, 1, poll.ParentID)
+	assert.Equal(t, CollectionType, poll.ParentType)
+	assert.Equal(t, "items", poll.Field)
+	assert.Equal(t, 2, poll.ContentID)
+	assert.Equal(t, ItemType("POLL"), poll.Type)
+	assert.True(t, errors.Is(poll.Reason, ErrUnknownType))
+
+	assert.Equal(t, ErrTeaserMissingTarget, dropped["items.1"].Reason)
+	assert.Equal(t, 3, dropped["items.1"].ContentID)
+
+	// a teaser dropped for its target is reported once, at the target
+	teaser := dropped["items.2.target"]
+	assert.Equal(t, 1, teaser.ParentID)
+	assert.Equal(t, "items", teaser.Field)
+	assert.Equal(t, 4, teaser.ContentID)
+	assert.Equal(t, ItemType(TeaserType), teaser.Type)
+	assert.True(t, errors.Is(teaser.Reason, ErrUnknownType))
+	_, ok := dropped["items.2"]
+	assert.False(t, ok)
+
+	media := dropped["items.3.media.0"]
+	assert.Equal(t, 6, media.ParentID)
+	assert.Equal(t, "media", media.Field)
+	assert.Equal(t, ItemType(UnsupportedType), media.Type)
+	assert.Equal(t, ErrUnsupportedType, media.Reason)
+}
+
+func TestShouldReportDroppedSubObjects(t *testing.T) {
+	svr, a := setupServerAndAPI(collectionWithBrokenItemsJSON)
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	assertBrokenItemsReport(t, item)
+}
+
+func TestShouldReportDroppedSubObjectsWhenStreaming(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI(collectionWithBrokenItemsJSON, true)
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	assertBrokenItemsReport(t, item)
+}
+
+func TestShouldNotReportCompleteItems(t *testing.T) {
+	svr, a := setupServerAndAPI(articleJSON)
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, Report(item))
+	assert.True(t, Report(item).Complete())
+}
+
+func TestShouldShareReportAcrossArrayResponse(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		svr, a, _ := setupDecodingServerAndAPI(`[{"type":"POLL","content_id":2},`+imageJSON+`,`+imageJSON+`]`, streaming)
+
+		items, err := a.ContentItems("someKrazyChannel", 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(items))
+		report := Report(items[0])
+		assert.Equal(t, report, Report(items[1]))
+		assert.Equal(t, 1, len(report.Dropped))
+		assert.Equal(t, "0", report.Dropped[0].Path)
+		assert.Equal(t, "", report.Dropped[0].Field)
+		assert.Equal(t, 0, report.Dropped[0].ParentID)
+		assert.Equal(t, 2, report.Dropped[0].ContentID)
+
+		svr.Close()
+	}
+}
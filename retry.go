@@ -0,0 +1,194 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how requests to IB are retried when they fail with a transport
+// error or a retryable HTTP status. The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per request, including the first one
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles with every further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts, including delays requested by
+	// a Retry-After header. Zero means no cap.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0 to 1) of each delay that is randomized
+	Jitter float64
+	// RetryableStatuses are the HTTP status codes that cause a request to be retried
+	RetryableStatuses []int
+	// OnRetry, if set, is called before every retry with the number of the attempt
+	// that failed, its error and the delay before the next attempt
+	OnRetry func(attempt int, err error, delay time.Duration)
+	// OnAttempt, if set, is called after every attempt with the context of the call, the
+	// number of the attempt and its error, which is nil for the attempt that succeeded
+	OnAttempt func(ctx context.Context, attempt int, err error)
+}
+
+// DefaultRetryPolicy retries dropped connections and gateway errors up to three attempts
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	BaseDelay:         100 * time.Millisecond,
+	MaxDelay:          2 * time.Second,
+	Jitter:            0.5,
+	RetryableStatuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+}
+
+// WithRetryPolicy enables retries for all requests made by the API
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(a *api) {
+		a.retryPolicy = policy
+	}
+}
+
+// RetryError is returned when a request still fails after being retried. It records
+// the number of attempts made and wraps the error of the last one. OnAttempt reports the
+// attempts made for requests that succeed.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// shouldRetry reports whether a request that failed with err is worth another attempt.
+// Network errors are retried unless the caller's context is done; IB errors are retried
+// only for the configured statuses. Anything else, such as a malformed URI, a request
+// refused locally or a response too large or malformed, would fail again.
+func (p *RetryPolicy) shouldRetry(ctx context.Context, err error) bool {
+	var de *DecodeError
+	if ctx.Err() != nil || errors.As(err, &de) {
+		return false
+	}
+
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		return isNetworkError(err)
+	}
+	for _, status := range p.RetryableStatuses {
+		if he.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// isNetworkError reports whether err was caused by the connection to IB, as opposed to
+// the request itself
+func isNetworkError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	// every error returned by http.Client is a *url.Error, which is a net.Error itself
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = ue.Err
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+// delay returns how long to wait after the given failed attempt. A Retry-After header
+// sent by IB takes precedence over the exponential backoff.
+func (p *RetryPolicy) delay(attempt int, err error) time.Duration {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		if d, ok := parseRetryAfter(he.retryAfter, time.Now()); ok {
+			return p.capDelay(d)
+		}
+	}
+
+	// stop doubling before the delay overflows, even without a cap
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	d = p.capDelay(d)
+
+	if p.Jitter > 0 && d > 0 {
+		jitter := time.Duration(float64(d) * p.Jitter)
+		if jitter < 0 || jitter >= d {
+			// Jitter above 1, or float rounding close to the largest duration
+			jitter = d - 1
+		}
+		d = d - jitter + time.Duration(rand.Int63n(int64(jitter)+1))
+	}
+
+	return d
+}
+
+func (p *RetryPolicy) capDelay(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// maxRetryAfterSeconds is the longest Retry-After, in seconds, a time.Duration can hold
+const maxRetryAfterSeconds = math.MaxInt64 / int64(time.Second)
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		if seconds > maxRetryAfterSeconds {
+			// would overflow a time.Duration
+			seconds = maxRetryAfterSeconds
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,191 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	BaseDelay:         time.Millisecond,
+	MaxDelay:          5 * time.Millisecond,
+	RetryableStatuses: []int{502, 503, 504},
+}
+
+func Test_doGet_shouldRetryRetryableStatus(t *testing.T) {
+	var calls int32
+	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "I leik milk!!1")
+	}))
+	defer testSvr.Close()
+
+	var retried, attempted []int
+	var lastErr error
+	policy := testRetryPolicy
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		retried = append(retried, attempt)
+	}
+	policy.OnAttempt = func(ctx context.Context, attempt int, err error) {
+		attempted = append(attempted, attempt)
+		lastErr = err
+	}
+	a := NewAPI(WithRetryPolicy(policy)).(*api)
+
+	resp, err := a.doGet(context.Background(), &request{uri: testSvr.URL})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("I leik milk!!1"), resp.body)
+	assert.Equal(t, int32(3), calls)
+	assert.Equal(t, []int{1, 2}, retried)
+	assert.Equal(t, []int{1, 2, 3}, attempted)
+	assert.Nil(t, lastErr)
+}
+
+func Test_doGet_shouldGiveUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer testSvr.Close()
+
+	a := NewAPI(WithRetryPolicy(testRetryPolicy)).(*api)
+
+	_, err := a.doGet(context.Background(), &request{uri: testSvr.URL})
+	var retryErr *RetryError
+	assert.True(t, errors.As(err, &retryErr))
+	assert.Equal(t, 3, retryErr.Attempts)
+	assert.Equal(t, int32(3), calls)
+}
+
+func Test_doGet_shouldNotRetryOtherStatuses(t *testing.T) {
+	var calls int32
+	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "I'm a teapot!", http.StatusTeapot)
+	}))
+	defer testSvr.Close()
+
+	a := NewAPI(WithRetryPolicy(testRetryPolicy)).(*api)
+
+	_, err := a.doGet(context.Background(), &request{uri: testSvr.URL})
+	assert.Equal(t, "IB returned an error: 418 I'm a teapot: "+testSvr.URL, err.Error())
+	assert.Equal(t, int32(1), calls)
+}
+
+func Test_doGet_shouldRetryConnectionErrors(t *testing.T) {
+	testSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := testSvr.URL
+	testSvr.Close()
+
+	a := NewAPI(WithRetryPolicy(testRetryPolicy)).(*api)
+
+	_, err := a.doGet(context.Background(), &request{uri: url})
+	var retryErr *RetryError
+	assert.True(t, errors.As(err, &retryErr))
+	assert.Equal(t, 3, retryErr.Attempts)
+}
+
+func Test_doGet_shouldRetryOnlyNetworkErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		attempts int
+	}{
+		{io.ErrUnexpectedEOF, 3},
+		{syscall.ECONNRESET, 3},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")}, 3},
+		{errors.New("permanent"), 1},
+	} {
+		var calls int32
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, tc.err
+		})
+		a := NewAPI(WithRetryPolicy(testRetryPolicy), WithTransport(transport)).(*api)
+
+		_, err := a.doGet(context.Background(), &request{uri: "http://ib.example.com/"})
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(tc.attempts), calls, tc.err.Error())
+	}
+}
+
+func Test_doGet_shouldNotRetryMalformedURIs(t *testing.T) {
+	var attempts int
+	policy := testRetryPolicy
+	policy.OnAttempt = func(ctx context.Context, attempt int, err error) { attempts = attempt }
+	a := NewAPI(WithRetryPolicy(policy)).(*api)
+
+	_, err := a.doGet(context.Background(), &request{uri: "http://[::1"})
+	var retryErr *RetryError
+	assert.NotNil(t, err)
+	assert.False(t, errors.As(err, &retryErr))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	assert.Equal(t, 10*time.Millisecond, p.delay(1, errors.New("boom")))
+	assert.Equal(t, 20*time.Millisecond, p.delay(2, errors.New("boom")))
+	assert.Equal(t, 40*time.Millisecond, p.delay(3, errors.New("boom")))
+	assert.Equal(t, 50*time.Millisecond, p.delay(4, errors.New("boom")))
+
+	assert.Equal(t, time.Duration(0), (&RetryPolicy{BaseDelay: time.Second}).delay(1, &HTTPError{retryAfter: "0"}))
+	assert.Equal(t, time.Minute, (&RetryPolicy{MaxDelay: time.Minute}).delay(1, &HTTPError{retryAfter: "120"}))
+
+	p.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		d := p.delay(1, errors.New("boom"))
+		assert.True(t, d >= 5*time.Millisecond && d <= 10*time.Millisecond, d.String())
+	}
+}
+
+func TestRetryPolicyDelayShouldNotOverflow(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second}
+	d := p.delay(100, errors.New("boom"))
+	assert.True(t, d > 1<<62, d.String())
+
+	p.Jitter = 1
+	for _, attempt := range []int{1, 40, 63, 64, 1000} {
+		d := p.delay(attempt, errors.New("boom"))
+		assert.True(t, d > 0, d.String())
+	}
+
+	p.Jitter = 2
+	assert.True(t, p.delay(1, errors.New("boom")) > 0)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2016, 8, 27, 12, 0, 0, 0, time.UTC)
+
+	d, ok := parseRetryAfter("7", now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, d)
+
+	d, ok = parseRetryAfter("Sat, 27 Aug 2016 12:00:30 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+
+	d, ok = parseRetryAfter("99999999999", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(maxRetryAfterSeconds)*time.Second, d)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
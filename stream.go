@@ -0,0 +1,472 @@
+package goib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// WithStreamingDecode decodes array responses, such as those of ContentItems, straight
+// from the HTTP response body one element at a time instead of buffering them first. Each
+// element is turned into an Item as soon as it has been read, so neither the whole body
+// nor the complete Receiver tree is ever held. Single-object responses are decoded as
+// usual, as an object has to be read whole before it can be decoded anyway.
+// Cached and coalesced responses have to be kept whole, so streaming is turned off when
+// combined with WithCache or WithRequestCoalescing. NewAPI logs a warning when that
+// happens.
+func WithStreamingDecode() Option {
+	return func(a *api) {
+		a.streaming = true
+	}
+}
+
+// canStream reports whether responses can be decoded while they are being read
+func (api *api) canStream() bool {
+	return api.streaming && len(api.bufferingOptions()) == 0
+}
+
+// bufferingOptions lists the options that keep responses from being streamed
+func (api *api) bufferingOptions() (result []string) {
+	if api.cache != nil {
+		result = append(result, "WithCache")
+	}
+	if api.flights != nil {
+		result = append(result, "WithRequestCoalescing")
+	}
+	return result
+}
+
+// warnIfBuffered logs a warning if streaming was asked for but is turned off by other
+// options
+func (api *api) warnIfBuffered() {
+	if api.streaming && !api.canStream() {
+		api.logger.Warn("streaming decode turned off, responses are buffered",
+			"options", strings.Join(api.bufferingOptions(), ", "))
+	}
+}
+
+// streamArray makes req decode its response as an array of objects while it is being
+// read, if responses don't have to be buffered
+func (api *api) streamArray(req *request) *request {
+	if api.canStream() {
+		req.stream = func(body io.Reader) (*response, error) {
+			items, err := api.streamItems(body, req)
+			if err != nil {
+				return nil, err
+			}
+			return &response{items: items}, nil
+		}
+	}
+	return req
+}
+
+type receiverStream struct {
+	api *api
+	dec *json.Decoder
+	req *request
+	// keepRaw is set if the raw JSON of every object is kept
+	keepRaw bool
+}
+
+// streamNodes holds nodes that can be reused for decoding further objects
+var streamNodes = sync.Pool{
+	New: func() interface{} { return new(streamNode) },
+}
+
+func (api *api) newReceiverStream(body io.Reader, req *request) *receiverStream {
+	s := &receiverStream{api: api, req: req, keepRaw: api.keepsRaw()}
+	if body != nil {
+		s.dec = json.NewDecoder(body)
+	}
+	return s
+}
+
+// decodeReceiver decodes a single IB object from data, unmarshalling its sub-objects as
+// soon as each of them has been read. The raw JSON kept for the object and its
+// sub-objects refers to data rather than copying it.
+func (api *api) decodeReceiver(data []byte, req *request) (Receiver, error) {
+	s := api.newReceiverStream(nil, req)
+	n := s.node(location{})
+	if err := json.Unmarshal(data, n); err != nil {
+		return Receiver{}, s.wrap("", err)
+	}
+	if s.keepRaw {
+		n.Receiver.raw = rawJSON(bytes.TrimSpace(data))
+	}
+	r := n.receiver()
+	s.release(n)
+	return r, nil
+}
+
+// streamItems decodes an array of IB objects from body, unmarshalling each one as soon as
+// it has been read. Objects that cannot be unmarshalled are dropped.
+func (api *api) streamItems(body io.Reader, req *request) (result []Item, err error) {
+	s := api.newReceiverStream(body, req)
+
+	if err = s.expectDelim("", '['); err != nil {
+		return nil, err
+	}
+	for i := 0; s.dec.More(); i++ {
+		at := location{index: i, indexed: true}
+		n := s.node(at)
+		if s.keepRaw {
+			// the decoder reuses its buffer, so the raw JSON has to be copied
+			var raw json.RawMessage
+			if err = s.dec.Decode(&raw); err == nil {
+				err = json.Unmarshal(raw, n)
+				n.Receiver.raw = raw
+			}
+		} else {
+			err = s.dec.Decode(n)
+		}
+		if err != nil {
+			return nil, s.wrap(at.String(), err)
+		}
+		if item, ok := s.arrayItem(n); ok {
+			result = append(result, item)
+		}
+		if req.failed() {
+			// the call fails with req.failure, the rest of the body doesn't matter
+			return nil, nil
+		}
+	}
+	if err = s.expectDelim("", ']'); err != nil {
+		return nil, err
+	}
+	if err = s.end(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// decodeItems decodes an array of IB objects from data like streamItems. The raw JSON
+// kept for objects refers to data rather than copying it.
+func (api *api) decodeItems(data []byte, req *request) (result []Item, err error) {
+	s := api.newReceiverStream(nil, req)
+
+	// elements are split off by hand, which needs valid JSON
+	if !json.Valid(data) {
+		var v interface{}
+		return nil, s.wrap("", json.Unmarshal(data, &v))
+	}
+	data = bytes.TrimSpace(data)
+	if data[0] != '[' {
+		return nil, s.decodeError("", errors.New("expected JSON array"))
+	}
+	i := 0
+	for elem, rest := nextElement(data[1:]); elem != nil; elem, rest = nextElement(rest) {
+		at := location{index: i, indexed: true}
+		n := s.node(at)
+		if err = json.Unmarshal(elem, n); err != nil {
+			return nil, s.wrap(at.String(), err)
+		}
+		n.Receiver.raw = rawJSON(elem)
+		if item, ok := s.arrayItem(n); ok {
+			result = append(result, item)
+		}
+		if req.failed() {
+			return nil, nil
+		}
+		i++
+	}
+
+	return result, nil
+}
+
+// arrayItem unmarshals the element of an array response decoded into n, releasing n. It
+// reports false if the element was dropped.
+func (s *receiverStream) arrayItem(n *streamNode) (Item, bool) {
+	r := n.receiver()
+	s.release(n)
+
+	item, err := s.api.UnmarshalReceiver(r)
+	if err != nil {
+		s.api.dropArrayItem(&r, item, err)
+		return nil, false
+	}
+	return item, true
+}
+
+// node returns a cleared node for decoding the object found at the given location
+func (s *receiverStream) node(at location) *streamNode {
+	n := streamNodes.Get().(*streamNode)
+	n.Receiver.req = s.req
+	n.Receiver.at = at
+	n.Items = childStream{s: s, at: at, field: "items"}
+	n.Media = childStream{s: s, at: at, field: "media"}
+	n.RelatedMedia = childStream{s: s, at: at, field: "related_media"}
+	n.Target = targetStream{s: s, at: at}
+	return n
+}
+
+// release hands back a node whose receiver has been taken
+func (s *receiverStream) release(n *streamNode) {
+	*n = streamNode{}
+	streamNodes.Put(n)
+}
+
+// streamNode decodes an IB object just like Receiver, except that its sub-objects are
+// unmarshalled into Items as soon as each of them has been read, so that no tree of
+// Receivers is ever built
+type streamNode struct {
+	Receiver
+	Items        childStream  `json:"items"`
+	Media        childStream  `json:"media"`
+	RelatedMedia childStream  `json:"related_media"`
+	Target       targetStream `json:"target"`
+}
+
+// receiver returns the decoded object, its sub-objects already unmarshalled
+func (n *streamNode) receiver() Receiver {
+	r := n.Receiver
+	r.Target = n.Target.r
+	if n.Items.present || n.Media.present || n.RelatedMedia.present {
+		r.streamed = &streamedChildren{
+			items:        n.Items.streamedField,
+			media:        n.Media.streamedField,
+			relatedMedia: n.RelatedMedia.streamedField,
+		}
+	}
+	return r
+}
+
+// streamedChildren holds the sub-objects of a streamed object
+type streamedChildren struct {
+	items, media, relatedMedia streamedField
+}
+
+func (c *streamedChildren) field(name string) *streamedField {
+	switch name {
+	case "items":
+		return &c.items
+	case "media":
+		return &c.media
+	case "related_media":
+		return &c.relatedMedia
+	}
+	return &streamedField{}
+}
+
+// streamedField holds the sub-objects of one field of a streamed object. Sub-objects
+// that couldn't be unmarshalled keep their place in items and are listed in failed, so
+// that the common case of no failures needs no further allocations.
+type streamedField struct {
+	present bool
+	items   []Item
+	failed  []failedChild
+}
+
+// failedChild is a sub-object that couldn't be unmarshalled, along with its index
+type failedChild struct {
+	index int
+	decodedChild
+}
+
+// childStream unmarshals the elements of a sub-object array one by one
+type childStream struct {
+	s     *receiverStream
+	at    location
+	field string
+	streamedField
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *childStream) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" || c.s.req.failed() {
+		return nil
+	}
+	if data[0] != '[' {
+		return c.s.decodeError(joinPath(c.at.String(), c.field), errors.New("expected JSON array"))
+	}
+
+	c.present = true
+	// split the array first, so that items can be allocated at once
+	var buf [64][]byte
+	elems := buf[:0]
+	for elem, rest := nextElement(data[1:]); elem != nil; elem, rest = nextElement(rest) {
+		elems = append(elems, elem)
+	}
+	if len(elems) == 0 {
+		return nil
+	}
+
+	parent := c.at.String()
+	c.items = make([]Item, 0, len(elems))
+	for i, elem := range elems {
+		at := location{parent: parent, field: c.field, index: i, indexed: true}
+		n := c.s.node(at)
+		if err := json.Unmarshal(elem, n); err != nil {
+			return c.s.wrap(at.String(), err)
+		}
+		if c.s.keepRaw {
+			n.Receiver.raw = rawJSON(elem)
+		}
+		r := n.receiver()
+		c.s.release(n)
+
+		item, err := c.s.api.UnmarshalReceiver(r)
+		if err != nil {
+			c.failed = append(c.failed, failedChild{i, newDecodedChild(&r, item, err)})
+		}
+		c.items = append(c.items, item)
+		// a strict request fails with the first dropped sub-object, so the rest can go
+		if c.s.req.failed() || err != nil && c.s.req.strict {
+			break
+		}
+	}
+	return nil
+}
+
+// targetStream decodes the target of a teaser
+type targetStream struct {
+	s  *receiverStream
+	at location
+	r  *Receiver
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *targetStream) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" || t.s.req.failed() {
+		return nil
+	}
+
+	at := location{parent: t.at.String(), field: "target"}
+	n := t.s.node(at)
+	if err := json.Unmarshal(data, n); err != nil {
+		return t.s.wrap(at.String(), err)
+	}
+	if t.s.keepRaw {
+		n.Receiver.raw = rawJSON(data)
+	}
+	r := n.receiver()
+	t.s.release(n)
+
+	t.r = &r
+	return nil
+}
+
+// nextElement splits off the next element of a valid JSON array, or the next member of a
+// valid JSON object, whose opening bracket has already been cut off, returning nil once
+// the closing bracket is reached. The element comes without surrounding whitespace.
+func nextElement(data []byte) (elem, rest []byte) {
+	start := 0
+	for start < len(data) && (isSpace(data[start]) || data[start] == ',') {
+		start++
+	}
+	if start == len(data) || data[start] == ']' || data[start] == '}' {
+		return nil, nil
+	}
+
+	depth := 0
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			i = skipString(data, i)
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return trimRightSpace(data[start:i]), data[i:]
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return trimRightSpace(data[start:i]), data[i:]
+			}
+		}
+	}
+	return trimRightSpace(data[start:]), nil
+}
+
+func trimRightSpace(data []byte) []byte {
+	end := len(data)
+	for end > 0 && isSpace(data[end-1]) {
+		end--
+	}
+	return data[:end]
+}
+
+// skipString returns the index of the closing quote of the string starting at i
+func skipString(data []byte, i int) int {
+	for {
+		end := bytes.IndexByte(data[i+1:], '"')
+		if end < 0 {
+			return len(data)
+		}
+		i += end + 1
+
+		// the quote is escaped if preceded by an odd number of backslashes
+		escapes := 0
+		for j := i - 1; data[j] == '\\'; j-- {
+			escapes++
+		}
+		if escapes%2 == 0 {
+			return i
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func (s *receiverStream) expectDelim(path string, delim json.Delim) error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return s.wrap(path, err)
+	}
+	if tok != delim {
+		return s.decodeError(path, fmt.Errorf("expected %v", delim))
+	}
+	return nil
+}
+
+// end makes sure nothing but whitespace follows the decoded value
+func (s *receiverStream) end() error {
+	if _, err := s.dec.Token(); err != io.EOF {
+		if err == nil {
+			return s.decodeError("", errors.New("unexpected data after top-level value"))
+		}
+		return s.wrap("", err)
+	}
+	return nil
+}
+
+// wrap turns JSON errors into DecodeErrors. Errors reading the body are returned as they
+// are, so that they are treated like any other transport error.
+func (s *receiverStream) wrap(path string, err error) error {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		// already wrapped by the sub-object that failed
+		return de
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return s.decodeError(joinPath(path, typeErr.Field), err)
+	}
+	if errors.As(err, &syntaxErr) || err == io.ErrUnexpectedEOF {
+		return s.decodeError(path, err)
+	}
+	return err
+}
+
+func (s *receiverStream) decodeError(path string, err error) error {
+	return &DecodeError{ContentID: s.req.contentID, Path: path, Err: err}
+}
+
+func joinPath(path, key string) string {
+	if path == "" || key == "" {
+		return path + key
+	}
+	return path + "." + key
+}
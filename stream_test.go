@@ -0,0 +1,209 @@
+package goib
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupDecodingServerAndAPI serves cannedResponse to an API that streams responses if
+// streaming is set, counting the calls made
+func setupDecodingServerAndAPI(cannedResponse string, streaming bool, opts ...Option) (*httptest.Server, API, *int32) {
+	var calls int32
+	if streaming {
+		opts = append(opts, WithStreamingDecode())
+	}
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(cannedResponse))
+	}, opts...)
+
+	return testSvr, a, &calls
+}
+
+func TestDecodeReceiverShouldMatchBufferedDecode(t *testing.T) {
+	fixtures := map[string]string{
+		"collection": multitieredCollectionJSON,
+		"article":    articleJSON,
+		"video":      videoJSON,
+		"entry":      entryJSON,
+		"search":     searchJSON,
+		"gallery":    galleryJSON,
+		"image":      imageJSON,
+		"settings":   collectionWithSettingsJSON,
+		"map":        mapJSON,
+		"person":     personJSON,
+		"livevideo":  livevideoJSON,
+		"teaser":     teaserJSON,
+	}
+
+	a := NewAPI().(*api)
+	req := &request{}
+	for name, fixture := range fixtures {
+		expected, err := a.unmarshalResponse(&response{body: []byte(fixture)}, req)
+		assert.Nil(t, err, name)
+
+		// sub-objects are unmarshalled while decoding, as for the elements of a streamed array
+		r, err := a.decodeReceiver([]byte(fixture), req)
+		assert.Nil(t, err, name)
+		r.req = req
+		actual, err := a.UnmarshalReceiver(r)
+		assert.Nil(t, err, name)
+
+		assert.Equal(t, expected, actual, name)
+	}
+}
+
+func TestStreamingDecodeShouldMatchBufferedDecodeForArrays(t *testing.T) {
+	a := NewAPI().(*api)
+	req := &request{}
+	array := "[" + articleJSON + "," + imageJSON + ", null," + videoJSON + "]"
+
+	expected, err := a.unmarshalArrayResponse(&response{body: []byte(array)}, req)
+	assert.Nil(t, err)
+
+	items, err := a.streamItems(strings.NewReader(array), req)
+	assert.Nil(t, err)
+	actual, err := a.unmarshalArrayResponse(&response{items: items, streamed: true}, req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestShouldDecodeSingleObjectsWhenStreaming(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI(multitieredCollectionJSON, true)
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+
+	c, ok := item.(*Collection)
+	assert.True(t, ok)
+	assert.Equal(t, 14277682, c.ContentID)
+	assert.Equal(t, 4, len(c.Items))
+}
+
+func TestShouldStreamContentMediaFromIB(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI("["+imageJSON+","+imageJSON+"]", true)
+	defer svr.Close()
+
+	items, err := a.ContentMedia("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, 29283344, items[1].GetContentID())
+}
+
+func TestStreamingDecodeShouldReportMalformedResponses(t *testing.T) {
+	a := NewAPI().(*api)
+
+	_, err := a.streamItems(strings.NewReader(emptyJSON), &request{contentID: 12345})
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, 12345, de.ContentID)
+
+	_, err = a.streamItems(strings.NewReader("["+imageJSON), &request{contentID: 12345})
+	assert.True(t, errors.As(err, &de))
+
+	_, err = a.streamItems(strings.NewReader(`[{"type":"collection","items":[{"content_id":"nope"}]}]`), &request{contentID: 12345})
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "0.items.0.content_id", de.Path)
+
+	_, err = a.streamItems(strings.NewReader(`{}`), &request{contentID: 12345})
+	assert.True(t, errors.As(err, &de))
+}
+
+func TestDecodeReceiverShouldReportMalformedResponses(t *testing.T) {
+	a := NewAPI().(*api)
+
+	_, err := a.decodeReceiver([]byte(emptyJSON), &request{contentID: 12345})
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, 12345, de.ContentID)
+
+	_, err = a.decodeReceiver([]byte(missingCloseBracketJSON), &request{contentID: 12345})
+	assert.True(t, errors.As(err, &de))
+
+	_, err = a.decodeReceiver([]byte(`{"type":"collection","items":[{"content_id":"nope"}]}`), &request{contentID: 12345})
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "items.0.content_id", de.Path)
+
+	_, err = a.decodeReceiver([]byte(`[]`), &request{contentID: 12345})
+	assert.True(t, errors.As(err, &de))
+}
+
+func TestShouldNotRetryMalformedStreamedResponses(t *testing.T) {
+	svr, a, calls := setupDecodingServerAndAPI("["+imageJSON, true, WithRetryPolicy(testRetryPolicy))
+	defer svr.Close()
+
+	_, err := a.ContentItems("someKrazyChannel", 12345, nil)
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestShouldLimitStreamedResponseSize(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI("["+galleryJSON+"]", true, WithMaxBodySize(512))
+	defer svr.Close()
+
+	_, err := a.ContentItems("someKrazyChannel", 12345, nil)
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+}
+
+func TestShouldBufferResponsesWhenCaching(t *testing.T) {
+	a := NewAPI(WithStreamingDecode(), WithCache(CacheConfig{})).(*api)
+	assert.False(t, a.canStream())
+	assert.Nil(t, a.streamArray(&request{}).stream)
+
+	a = NewAPI(WithStreamingDecode()).(*api)
+	assert.True(t, a.canStream())
+}
+
+// benchmarkArray is an array response of many small objects, like ContentItems returns
+var benchmarkArray = func() string {
+	items := make([]string, 200)
+	for i := range items {
+		items[i] = imageJSON
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}()
+
+func BenchmarkBufferedArrayDecode(b *testing.B) {
+	a := NewAPI().(*api)
+	req := &request{}
+	payload := []byte(benchmarkArray)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		body, err := ioutil.ReadAll(bytes.NewReader(payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := a.unmarshalArrayResponse(&response{body: body}, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStreamingArrayDecode(b *testing.B) {
+	a := NewAPI().(*api)
+	req := &request{}
+	payload := []byte(benchmarkArray)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		items, err := a.streamItems(bytes.NewReader(payload), req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := a.unmarshalArrayResponse(&response{items: items, streamed: true}, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
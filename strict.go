@@ -0,0 +1,28 @@
+package goib
+
+import "context"
+
+// WithStrictDecoding makes calls fail with a *DecodeError as soon as a sub-object anywhere
+// in the response can't be unmarshalled, instead of dropping it. This covers unknown and
+// unsupported types, teasers without a target and sub-objects rejected by their decoder;
+// the error's Path locates the offending node, e.g. "items.3.target". Objects kept as
+// GenericItems because of WithGenericItems don't count as errors.
+func WithStrictDecoding() Option {
+	return func(a *api) {
+		a.strict = true
+	}
+}
+
+type strictDecodingKey struct{}
+
+// ContextWithStrictDecoding returns a context that makes a single call decode strictly,
+// as if the API had been constructed with WithStrictDecoding
+func ContextWithStrictDecoding(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictDecodingKey{}, true)
+}
+
+// decodesStrictly reports whether a call made with ctx fails on any dropped sub-object
+func (api *api) decodesStrictly(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictDecodingKey{}).(bool)
+	return api.strict || strict
+}
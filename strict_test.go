@@ -0,0 +1,119 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var strictFixtures = []struct {
+	json      string
+	path      string
+	contentID int
+	reason    error
+}{
+	{`{"type":"COLLECTION","content_id":1,"items":[` + imageJSON + `,{"type":"POLL","content_id":2}]}`, "items.1", 2, ErrUnknownType},
+	{`{"type":"COLLECTION","content_id":1,"items":[{"type":"TEASER","content_id":3}]}`, "items.0", 3, ErrTeaserMissingTarget},
+	{`{"type":"COLLECTION","content_id":1,"items":[{"type":"TEASER","content_id":4,"target":{"type":"POLL"}}]}`, "items.0.target", 4, ErrUnknownType},
+	{`{"type":"ARTICLE","content_id":1,"media":[{"type":"UNSUPPORTED"}]}`, "media.0", 1, ErrUnsupportedType},
+	{`{"type":"TEASER","content_id":1,"target":{"type":"POLL"}}`, "target", 1, ErrUnknownType},
+}
+
+func TestShouldFailOnDroppedSubObjectWhenStrict(t *testing.T) {
+	for _, fixture := range strictFixtures {
+		svr, a, _ := setupDecodingServerAndAPI(fixture.json, true, WithStrictDecoding())
+
+		item, err := a.Content("someKrazyChannel", 1, nil)
+		assert.Nil(t, item, fixture.path)
+		var de *DecodeError
+		assert.True(t, errors.As(err, &de), fixture.path)
+		assert.Equal(t, fixture.contentID, de.ContentID, fixture.path)
+		assert.Equal(t, fixture.path, de.Path)
+		assert.True(t, errors.Is(err, fixture.reason), fixture.path)
+
+		svr.Close()
+	}
+}
+
+func TestShouldDecodeStrictlyPerCall(t *testing.T) {
+	for _, fixture := range strictFixtures[:4] {
+		svr, a := setupServerAndAPI(fixture.json)
+
+		item, err := a.Entry("someKrazyChannel", "home", nil)
+		assert.Nil(t, err, fixture.path)
+		assert.NotNil(t, item, fixture.path)
+
+		item, err = a.(APIContext).EntryContext(ContextWithStrictDecoding(context.Background()), "someKrazyChannel", "home", nil)
+		assert.Nil(t, item, fixture.path)
+		var de *DecodeError
+		assert.True(t, errors.As(err, &de), fixture.path)
+		assert.Equal(t, fixture.contentID, de.ContentID, fixture.path)
+		assert.Equal(t, fixture.path, de.Path)
+
+		svr.Close()
+	}
+}
+
+func TestShouldFailArrayResponseWhenStrict(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI(`[`+imageJSON+`,{"type":"POLL","content_id":2}]`, true, WithStrictDecoding())
+	defer svr.Close()
+
+	items, err := a.ContentItems("someKrazyChannel", 1, nil)
+	assert.Nil(t, items)
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "1", de.Path)
+	assert.Equal(t, 2, de.ContentID)
+}
+
+func TestShouldStopWarningOnceStrictDecodingFailed(t *testing.T) {
+	for _, fixture := range []string{
+		`{"type":"COLLECTION","content_id":1,"items":[{"type":"POLL","content_id":2},{"type":"TEASER","content_id":3},` + imageJSON + `]}`,
+		`[{"type":"POLL","content_id":2},{"type":"TEASER","content_id":3},` + imageJSON + `]`,
+	} {
+		for _, streaming := range []bool{false, true} {
+			logger := &recordingLogger{}
+			opts := []Option{WithStrictDecoding(), WithLogger(logger)}
+			if streaming {
+				opts = append(opts, WithStreamingDecode())
+			}
+			svr, a, metrics := setupMetricsServerAndAPI(fixture, 200, opts...)
+
+			var err error
+			if fixture[0] == '[' {
+				_, err = a.ContentItems("someKrazyChannel", 1, nil)
+			} else {
+				_, err = a.Content("someKrazyChannel", 1, nil)
+			}
+			var de *DecodeError
+			assert.True(t, errors.As(err, &de))
+			assert.Equal(t, 2, de.ContentID)
+			assert.True(t, errors.Is(err, ErrUnknownType))
+			assert.Empty(t, logger.warnings())
+			assert.Empty(t, metrics.warnings)
+
+			svr.Close()
+		}
+	}
+}
+
+func TestShouldKeepGenericItemsWhenStrict(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI(strictFixtures[0].json, false, WithStrictDecoding(), WithGenericItems())
+	defer svr.Close()
+
+	item, err := a.Content("someKrazyChannel", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(item.(*Collection).Items))
+}
+
+func TestShouldFailOnBadNestedStructureWhenStrict(t *testing.T) {
+	svr, a, _ := setupDecodingServerAndAPI(`{"type":"COLLECTION","content_id":1,"items":[{"type":"IMAGE","content_id":"x"}]}`, true, WithStrictDecoding())
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 1, nil)
+	var de *DecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "items.0.content_id", de.Path)
+}
@@ -0,0 +1,167 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Tracer starts spans around the work done for calls to IB. The returned context carries
+// the new span and is passed on to everything the span covers, so spans started by the
+// caller, by nested calls and by transport middlewares all end up in one tree.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a unit of work started by a Tracer
+type Span interface {
+	SetAttribute(key string, value interface{})
+	End(err error)
+}
+
+// Attributes set on the spans started for calls to IB
+const (
+	AttrChannel   = "goib.channel"
+	AttrService   = "goib.service"
+	AttrContentID = "goib.content_id"
+	AttrURI       = "goib.uri"
+	AttrHost      = "goib.host"
+	AttrStatus    = "http.status_code"
+)
+
+// WithTracer traces every call to IB with the given Tracer. Each call gets a span named
+// after its service, e.g. "goib.content", with a child span named "goib.http" for every
+// HTTP request sent to IB, including retries.
+func WithTracer(t Tracer) Option {
+	return func(a *api) {
+		a.tracer = t
+	}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) End(err error)                              {}
+
+// startSpan starts a span named name for req, or a no-op span if tracing is disabled
+func (api *api) startSpan(ctx context.Context, name string, req *request) (context.Context, Span) {
+	if api.tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	ctx, span := api.tracer.Start(ctx, name)
+	span.SetAttribute(AttrChannel, req.channel)
+	span.SetAttribute(AttrService, req.service)
+	if req.contentID != 0 {
+		span.SetAttribute(AttrContentID, req.contentID)
+	}
+	span.SetAttribute(AttrURI, req.uri)
+	return ctx, span
+}
+
+// endSpan records the status of the outcome of a request on span and ends it
+func endSpan(span Span, resp *response, err error) {
+	var he *HTTPError
+	switch {
+	case resp != nil && resp.notModified:
+		span.SetAttribute(AttrStatus, http.StatusNotModified)
+	case resp != nil:
+		span.SetAttribute(AttrStatus, http.StatusOK)
+	case errors.As(err, &he):
+		span.SetAttribute(AttrStatus, he.StatusCode)
+	}
+	span.End(err)
+}
+
+// RecordedSpan is a span kept by a RecordingTracer
+type RecordedSpan struct {
+	ID         int
+	ParentID   int // 0 for root spans
+	Name       string
+	Attributes map[string]interface{}
+	Err        error
+	Start      time.Time
+	End        time.Time
+	Ended      bool
+}
+
+// RecordingTracer is a Tracer that keeps every span in memory, which is mainly useful in tests
+type RecordingTracer struct {
+	mu     sync.Mutex
+	spans  []*RecordedSpan
+	nextID int
+}
+
+// NewRecordingTracer constructs an empty RecordingTracer
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+type recordingSpanKey struct{}
+
+type recordingSpan struct {
+	t    *RecordingTracer
+	span *RecordedSpan
+}
+
+// Start implements Tracer. The new span is a child of the RecordingTracer span in ctx, if any.
+func (t *RecordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	var parentID int
+	if parent, ok := ctx.Value(recordingSpanKey{}).(*recordingSpan); ok && parent.t == t {
+		parentID = parent.span.ID
+	}
+
+	t.mu.Lock()
+	t.nextID++
+	span := &recordingSpan{t: t, span: &RecordedSpan{
+		ID:         t.nextID,
+		ParentID:   parentID,
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		Start:      time.Now(),
+	}}
+	t.spans = append(t.spans, span.span)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, recordingSpanKey{}, span), span
+}
+
+// Spans returns a copy of all spans started so far, in the order they were started
+func (t *RecordingTracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]RecordedSpan, len(t.spans))
+	for i, span := range t.spans {
+		result[i] = *span
+		result[i].Attributes = make(map[string]interface{}, len(span.Attributes))
+		for k, v := range span.Attributes {
+			result[i].Attributes[k] = v
+		}
+	}
+	return result
+}
+
+// Reset forgets all spans recorded so far. Spans that are still running are not recorded
+// once they end.
+func (t *RecordingTracer) Reset() {
+	t.mu.Lock()
+	t.spans = nil
+	t.mu.Unlock()
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.t.mu.Lock()
+	s.span.Attributes[key] = value
+	s.t.mu.Unlock()
+}
+
+func (s *recordingSpan) End(err error) {
+	s.t.mu.Lock()
+	s.span.Err = err
+	s.span.End = time.Now()
+	s.span.Ended = true
+	s.t.mu.Unlock()
+}
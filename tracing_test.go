@@ -0,0 +1,117 @@
+package goib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTracingServerAndAPI(status *int32, opts ...Option) (*httptest.Server, API, *RecordingTracer) {
+	tracer := NewRecordingTracer()
+	testSvr, a := setupServerAndAPIWithOptions(func(w http.ResponseWriter, r *http.Request) {
+		if s := int(atomic.LoadInt32(status)); s != http.StatusOK {
+			w.WriteHeader(s)
+			return
+		}
+		w.Write([]byte(imageJSON))
+	}, append([]Option{WithTracer(tracer)}, opts...)...)
+
+	return testSvr, a, tracer
+}
+
+func TestShouldTraceNestedCalls(t *testing.T) {
+	status := int32(http.StatusOK)
+	svr, a, tracer := setupTracingServerAndAPI(&status)
+	defer svr.Close()
+
+	ctx, root := tracer.Start(context.Background(), "page build")
+	_, err := a.EntryContext(ctx, "someKrazyChannel", "home", nil)
+	assert.Nil(t, err)
+	_, err = a.ContentContext(ctx, "someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	root.End(nil)
+
+	spans := tracer.Spans()
+	assert.Equal(t, 5, len(spans))
+
+	assert.Equal(t, "page build", spans[0].Name)
+	assert.Equal(t, 0, spans[0].ParentID)
+
+	entry, entryHTTP := spans[1], spans[2]
+	assert.Equal(t, "goib.entry", entry.Name)
+	assert.Equal(t, spans[0].ID, entry.ParentID)
+	assert.Equal(t, "someKrazyChannel", entry.Attributes[AttrChannel])
+	assert.Equal(t, "entry", entry.Attributes[AttrService])
+	assert.Equal(t, http.StatusOK, entry.Attributes[AttrStatus])
+	assert.Nil(t, entry.Attributes[AttrContentID])
+	assert.Equal(t, "goib.http", entryHTTP.Name)
+	assert.Equal(t, entry.ID, entryHTTP.ParentID)
+
+	content := spans[3]
+	assert.Equal(t, "goib.content", content.Name)
+	assert.Equal(t, spans[0].ID, content.ParentID)
+	assert.Equal(t, 12345, content.Attributes[AttrContentID])
+	assert.Contains(t, content.Attributes[AttrURI], "/content/12345")
+	assert.Equal(t, content.ID, spans[4].ParentID)
+
+	for _, span := range spans {
+		assert.True(t, span.Ended, span.Name)
+	}
+}
+
+func TestShouldTraceEachAttempt(t *testing.T) {
+	status := int32(http.StatusServiceUnavailable)
+	svr, a, tracer := setupTracingServerAndAPI(&status, WithRetryPolicy(testRetryPolicy))
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.NotNil(t, err)
+
+	spans := tracer.Spans()
+	assert.Equal(t, 1+testRetryPolicy.MaxAttempts, len(spans))
+	assert.Equal(t, http.StatusServiceUnavailable, spans[0].Attributes[AttrStatus])
+	var re *RetryError
+	assert.True(t, errors.As(spans[0].Err, &re))
+	for _, span := range spans[1:] {
+		assert.Equal(t, "goib.http", span.Name)
+		assert.Equal(t, spans[0].ID, span.ParentID)
+		assert.Equal(t, http.StatusServiceUnavailable, span.Attributes[AttrStatus])
+		assert.NotNil(t, span.Attributes[AttrHost])
+	}
+}
+
+func TestShouldPropagateSpanToTransport(t *testing.T) {
+	status := int32(http.StatusOK)
+	tracer := NewRecordingTracer()
+	var transportParent int
+	mw := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			_, span := tracer.Start(req.Context(), "transport")
+			span.End(nil)
+			transportParent = tracer.Spans()[2].ParentID
+			return next.RoundTrip(req)
+		})
+	}
+	svr, a, _ := setupTracingServerAndAPI(&status, WithTracer(tracer), Use(mw))
+	defer svr.Close()
+
+	_, err := a.Content("someKrazyChannel", 12345, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, transportParent)
+}
+
+func TestRecordingTracerReset(t *testing.T) {
+	tracer := NewRecordingTracer()
+	_, span := tracer.Start(context.Background(), "one")
+	span.SetAttribute("k", "v")
+	span.End(nil)
+	assert.Equal(t, "v", tracer.Spans()[0].Attributes["k"])
+
+	tracer.Reset()
+	assert.Equal(t, 0, len(tracer.Spans()))
+}
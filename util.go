@@ -2,8 +2,6 @@ package goib
 
 import (
 	"strconv"
-
-	l5g "github.com/neocortical/log5go"
 )
 
 // maxMediaRecursionDepth is a safety valve to prevent infinite recursion when iterating over collection media
@@ -11,16 +9,23 @@ const maxMediaRecursionDepth = 5
 
 // ExtractMedia recursively scans the supplied object for simple (displayable) media types
 // A simple media item will produce a list containing that item. Collections and SearchResults
-// are recursively scanned for inner media
+// are recursively scanned for inner media. Unexpected types are logged through the
+// package-level logger.
 func ExtractMedia(input Item) (media []Item) {
+	return ExtractMediaWithLogger(input, packageLogger)
+}
+
+// ExtractMediaWithLogger works like ExtractMedia, logging unexpected types through the given
+// logger, e.g. the one an API was configured with
+func ExtractMediaWithLogger(input Item, logger Logger) (media []Item) {
 	media = []Item{}
 
-	extractMediaRecursive(input, &media)
+	extractMediaRecursive(input, &media, logger)
 
 	return media
 }
 
-func extractMediaRecursive(input Item, media *[]Item) {
+func extractMediaRecursive(input Item, media *[]Item, logger Logger) {
 	if input == nil {
 		return
 	}
@@ -40,10 +45,10 @@ func extractMediaRecursive(input Item, media *[]Item) {
 			return
 		}
 		for _, item := range c.Items {
-			extractMediaRecursive(item, media)
+			extractMediaRecursive(item, media, logger)
 		}
 	default:
-		log.Debug("unexpected type: %s", input.GetType())
+		logger.Debug("unexpected type", "item_type", input.GetType())
 		break
 	}
 
@@ -57,19 +62,25 @@ type MediaNode struct {
 }
 
 // MediaIterator iterates over an IB response, recursively returning all media referenced
-// therein in depth-first order
+// therein in depth-first order. Unexpected types are logged through the package-level logger.
 func MediaIterator(root Item) chan *MediaNode {
+	return MediaIteratorWithLogger(root, packageLogger)
+}
+
+// MediaIteratorWithLogger works like MediaIterator, logging unexpected types through the
+// given logger, e.g. the one an API was configured with
+func MediaIteratorWithLogger(root Item, logger Logger) chan *MediaNode {
 	ch := make(chan *MediaNode)
-	go iterateMedia(root, ch)
+	go iterateMedia(root, ch, logger)
 	return ch
 }
 
-func iterateMedia(root Item, ch chan *MediaNode) {
-	iterateMediaRecursive(root, nil, ch, 1)
+func iterateMedia(root Item, ch chan *MediaNode, logger Logger) {
+	iterateMediaRecursive(root, nil, ch, 1, logger)
 	close(ch)
 }
 
-func iterateMediaRecursive(node Item, parent *Collection, ch chan *MediaNode, depth int) {
+func iterateMediaRecursive(node Item, parent *Collection, ch chan *MediaNode, depth int, logger Logger) {
 	if node == nil || depth > maxMediaRecursionDepth {
 		return
 	}
@@ -82,10 +93,10 @@ func iterateMediaRecursive(node Item, parent *Collection, ch chan *MediaNode, de
 			return
 		}
 		for _, item := range t.Items {
-			iterateMediaRecursive(item, t, ch, depth+1)
+			iterateMediaRecursive(item, t, ch, depth+1, logger)
 		}
 	default:
-		log.Debug("unexpected type found during iteration: %s", node.GetType())
+		logger.Debug("unexpected type found during iteration", "item_type", node.GetType())
 
 	}
 
@@ -182,8 +193,3 @@ func GetIntSetting(settings map[string]string, key string, dflt int) int {
 	}
 	return result
 }
-
-// SetLog sets the package-level logger
-func SetLog(newLog l5g.Log5Go) {
-	log = newLog
-}
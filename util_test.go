@@ -135,3 +135,20 @@ func Test_MediaIteratorRecursion(t *testing.T) {
 	}
 	assert.True(t, i < maxMediaRecursionDepth)
 }
+
+func TestUtilitiesShouldLogThroughTheGivenLogger(t *testing.T) {
+	poll := &GenericItem{Type: "POLL"}
+	coll := &Collection{Items: []Item{&Map{}, poll}}
+
+	logger := &recordingLogger{}
+	result := ExtractMediaWithLogger(coll, logger)
+	assert.Equal(t, 0, len(result))
+	assert.Equal(t, 2, len(logger.entries))
+	assert.Equal(t, MapType, logger.entries[0].fields["item_type"])
+
+	logger = &recordingLogger{}
+	for range MediaIteratorWithLogger(coll, logger) {
+	}
+	assert.Equal(t, 1, len(logger.entries))
+	assert.Equal(t, ItemType("POLL"), logger.entries[0].fields["item_type"])
+}